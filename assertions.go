@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/tidwall/gjson"
+)
+
+var ASSERTION_SUBJECTS = []string{"status", "header", "json", "body", "duration"}
+
+var ASSERTION_OPERATORS = []string{"==", "!=", "<", "<=", ">", ">=", "contains", "matches", "exists"}
+
+// AssertionResult is the outcome of an assertion on a response
+type AssertionResult struct {
+	Assertion string
+	Passed    bool
+	Message   string `json:",omitempty"`
+}
+
+// assertion checks a part of a response, e.g.
+//
+//	status == 200
+//	header Content-Type contains json
+//	json data.user.id == 42
+//	body matches "token":\s*"\w+"
+//	duration < 500ms
+type assertion struct {
+	subject string
+	arg     string
+	op      string
+	value   string
+	pattern *regexp.Regexp
+}
+
+func parseAssertion(line string) (*assertion, error) {
+	rest := strings.TrimSpace(line)
+	next := func() string {
+		rest = strings.TrimLeft(rest, " \t")
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			i = len(rest)
+		}
+		token := rest[:i]
+		rest = rest[i:]
+		return token
+	}
+
+	as := &assertion{subject: strings.ToLower(next())}
+	if !slices.Contains(ASSERTION_SUBJECTS, as.subject) {
+		return nil, fmt.Errorf("Unknown assertion subject: %v", as.subject)
+	}
+	if as.subject == "header" || as.subject == "json" {
+		if as.arg = next(); as.arg == "" {
+			return nil, fmt.Errorf("No %v name specified", as.subject)
+		}
+	}
+	as.op = strings.ToLower(next())
+	if as.op == "" {
+		return nil, errors.New("No assertion operator specified")
+	}
+	if !slices.Contains(ASSERTION_OPERATORS, as.op) {
+		return nil, fmt.Errorf("Unknown assertion operator: %v", as.op)
+	}
+	as.value = strings.TrimSpace(rest)
+	if strings.HasPrefix(as.value, `"`) {
+		if value, err := strconv.Unquote(as.value); err == nil {
+			as.value = value
+		}
+	}
+
+	switch {
+	case as.op == "exists":
+		if as.subject != "header" && as.subject != "json" {
+			return nil, fmt.Errorf("Invalid operator for %v: exists", as.subject)
+		}
+		if as.value != "" {
+			return nil, errors.New("exists takes no value")
+		}
+		return as, nil
+	case as.value == "":
+		return nil, errors.New("No assertion value specified")
+	}
+
+	switch as.subject {
+	case "duration":
+		if _, err := strconv.ParseFloat(as.value, 64); err == nil {
+			// a number is a duration in milliseconds
+			as.value += "ms"
+		}
+		d, err := time.ParseDuration(as.value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid duration: %v", as.value)
+		}
+		as.value = strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	case "status":
+		if _, err := strconv.Atoi(as.value); err != nil {
+			return nil, fmt.Errorf("Invalid status code: %v", as.value)
+		}
+	}
+	if as.op == "matches" {
+		var err error
+		if as.pattern, err = regexp.Compile(as.value); err != nil {
+			return nil, fmt.Errorf("Invalid regular expression: %v", err)
+		}
+	}
+	return as, nil
+}
+
+// evaluate returns an error describing the failure of the assertion
+func (as *assertion) evaluate(r *Request) error {
+	var actual string
+	found := true
+	switch as.subject {
+	case "status":
+		actual = strconv.Itoa(r.StatusCode)
+	case "header":
+		var values []string
+		values, found = r.RawResponseHeaders[http.CanonicalHeaderKey(as.arg)]
+		actual = strings.Join(values, ",")
+	case "json":
+		result := gjson.GetBytes(r.RawResponseBody, as.arg)
+		found = result.Exists()
+		actual = result.String()
+	case "body":
+		actual = string(r.RawResponseBody)
+	case "duration":
+		actual = strconv.FormatFloat(float64(r.Duration)/float64(time.Millisecond), 'f', -1, 64)
+	}
+	if !found {
+		return errors.New("not found")
+	}
+
+	var passed bool
+	switch as.op {
+	case "exists":
+		return nil
+	case "==", "!=":
+		x, xErr := strconv.ParseFloat(actual, 64)
+		y, yErr := strconv.ParseFloat(as.value, 64)
+		if xErr == nil && yErr == nil {
+			passed = x == y
+		} else {
+			passed = actual == as.value
+		}
+		if as.op == "!=" {
+			passed = !passed
+		}
+	case "<", "<=", ">", ">=":
+		x, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return fmt.Errorf("not a number: %v", shortenAssertionValue(actual))
+		}
+		y, err := strconv.ParseFloat(as.value, 64)
+		if err != nil {
+			return fmt.Errorf("not a number: %v", as.value)
+		}
+		switch as.op {
+		case "<":
+			passed = x < y
+		case "<=":
+			passed = x <= y
+		case ">":
+			passed = x > y
+		case ">=":
+			passed = x >= y
+		}
+	case "contains":
+		passed = strings.Contains(actual, as.value)
+	case "matches":
+		passed = as.pattern.MatchString(actual)
+	}
+	if passed {
+		return nil
+	}
+	if as.subject == "duration" {
+		return fmt.Errorf("got %vms", actual)
+	}
+	if as.subject == "body" {
+		return errors.New("no match in the body")
+	}
+	return fmt.Errorf("got %v", shortenAssertionValue(actual))
+}
+
+func shortenAssertionValue(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	if len([]rune(s)) > 60 {
+		return string([]rune(s)[:60]) + "…"
+	}
+	return s
+}
+
+// evaluateAssertions checks the assertions of a request, one per line,
+// lines starting with # are comments
+func evaluateAssertions(r *Request) []AssertionResult {
+	var results []AssertionResult
+	for _, line := range strings.Split(r.Assertions, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result := AssertionResult{Assertion: line}
+		as, err := parseAssertion(line)
+		if err == nil {
+			err = as.evaluate(r)
+		}
+		if err != nil {
+			result.Message = err.Error()
+		} else {
+			result.Passed = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// assertionSummary returns the number of passed assertions of a request
+func assertionSummary(r *Request) string {
+	if len(r.AssertionResults) == 0 {
+		return ""
+	}
+	passed := 0
+	for _, result := range r.AssertionResults {
+		if result.Passed {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d/%d passed", passed, len(r.AssertionResults))
+}
+
+// ToggleAssertions edits the assertions checked on the responses of the
+// following requests
+func (a *App) ToggleAssertions(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == ASSERTIONS_VIEW {
+		a.closePopup(g, ASSERTIONS_VIEW)
+		return nil
+	}
+	editor, err := a.CreatePopupView(ASSERTIONS_VIEW, 80, 12, g)
+	if err != nil {
+		return err
+	}
+	g.Cursor = true
+	editor.Title = VIEW_TITLES[ASSERTIONS_VIEW]
+	editor.Editable = true
+	editor.Highlight = false
+	fmt.Fprint(editor, a.assertions)
+	g.SetViewOnTop(ASSERTIONS_VIEW)
+	g.SetCurrentView(ASSERTIONS_VIEW)
+	return nil
+}
+
+func (a *App) saveAssertions(g *gocui.Gui, v *gocui.View) error {
+	a.assertions = strings.TrimSpace(v.Buffer())
+	a.closePopup(g, ASSERTIONS_VIEW)
+	// invalid assertions are saved and fail
+	for i, line := range strings.Split(a.assertions, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := parseAssertion(line); err != nil {
+			return a.OpenSaveResultView(fmt.Sprintf("Invalid assertion in line %d: %v", i+1, err), g)
+		}
+	}
+	return nil
+}
+
+// ToggleAssertionResults shows the assertion results of the selected
+// request
+func (a *App) ToggleAssertionResults(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == ASSERTION_RESULTS_VIEW {
+		a.closePopup(g, ASSERTION_RESULTS_VIEW)
+		return nil
+	}
+	maxX, maxY := g.Size()
+	results, err := a.CreatePopupView(ASSERTION_RESULTS_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	results.Title = VIEW_TITLES[ASSERTION_RESULTS_VIEW]
+	results.Highlight = false
+	results.Wrap = true
+	g.SetViewOnTop(ASSERTION_RESULTS_VIEW)
+	g.SetCurrentView(ASSERTION_RESULTS_VIEW)
+
+	if len(a.history) == 0 {
+		setViewTextAndCursor(results, "[!] No request sent yet")
+		return nil
+	}
+	r := a.history[a.historyIndex]
+	if len(r.AssertionResults) == 0 {
+		setViewTextAndCursor(results, "[!] No assertions checked")
+		return nil
+	}
+	writeAssertionResults(results, r.AssertionResults)
+	return nil
+}
+
+func writeAssertionResults(v *gocui.View, results []AssertionResult) {
+	for _, result := range results {
+		if result.Passed {
+			fmt.Fprintf(v, "\x1b[0;32mPASS\x1b[0;0m %v\n", result.Assertion)
+		} else {
+			fmt.Fprintf(v, "\x1b[0;31mFAIL\x1b[0;0m %v: %v\n", result.Assertion, result.Message)
+		}
+	}
+}
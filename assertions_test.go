@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		line                    string
+		subject, arg, op, value string
+		err                     string
+	}{
+		{"status == 200", "status", "", "==", "200", ""},
+		{"  STATUS\t!=\t404 ", "status", "", "!=", "404", ""},
+		{"header Content-Type contains json", "header", "Content-Type", "contains", "json", ""},
+		{"header X-Token exists", "header", "X-Token", "exists", "", ""},
+		{`json data.name == "a b"`, "json", "data.name", "==", "a b", ""},
+		{`json data.name == "a`, "json", "data.name", "==", `"a`, ""},
+		{`body matches "token":\s*"\w+"`, "body", "", "matches", `"token":\s*"\w+"`, ""},
+		{"duration < 500", "duration", "", "<", "500", ""},
+		{"duration <= 1.5s", "duration", "", "<=", "1500", ""},
+		{"", "", "", "", "", "Unknown assertion subject: "},
+		{"cookie == a", "", "", "", "", "Unknown assertion subject: cookie"},
+		{"header", "", "", "", "", "No header name specified"},
+		{"json", "", "", "", "", "No json name specified"},
+		{"status", "", "", "", "", "No assertion operator specified"},
+		{"status = 200", "", "", "", "", "Unknown assertion operator: ="},
+		{"status ==", "", "", "", "", "No assertion value specified"},
+		{"status exists", "", "", "", "", "Invalid operator for status: exists"},
+		{"json id exists 1", "", "", "", "", "exists takes no value"},
+		{"status == OK", "", "", "", "", "Invalid status code: OK"},
+		{"duration < soon", "", "", "", "", "Invalid duration: soon"},
+		{"body matches (", "", "", "", "", "Invalid regular expression: error parsing regexp: missing closing ): `(`"},
+	}
+	for _, test := range tests {
+		as, err := parseAssertion(test.line)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Expected error %q for %q, got %v", test.err, test.line, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.line, err)
+			continue
+		}
+		if as.subject != test.subject || as.arg != test.arg || as.op != test.op || as.value != test.value {
+			t.Errorf("Unexpected assertion for %q: %+v", test.line, as)
+		}
+		if (as.op == "matches") != (as.pattern != nil) {
+			t.Errorf("Unexpected pattern for %q: %v", test.line, as.pattern)
+		}
+	}
+}
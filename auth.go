@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asciimoo/wuzz/config"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+const (
+	AUTH_BASIC  = "basic"
+	AUTH_BEARER = "bearer"
+	AUTH_DIGEST = "digest"
+	AUTH_OAUTH2 = "oauth2"
+
+	// tokens are renewed before they expire
+	OAUTH2_EXPIRY_DELTA = 30 * time.Second
+)
+
+// requestAuth is a configured auth with the variables of the active
+// environment substituted
+type requestAuth struct {
+	name string
+	config.Auth
+}
+
+type oauth2Token struct {
+	accessToken  string
+	tokenType    string
+	refreshToken string
+	// zero if the token does not expire
+	expiry time.Time
+}
+
+// oauth2Tokens caches the tokens of the OAuth2 auths by auth name, token
+// URL and client ID
+type oauth2Tokens struct {
+	sync.Mutex
+	tokens map[string]*oauth2Token
+}
+
+func (t *oauth2Token) valid() bool {
+	return t != nil && (t.expiry.IsZero() || time.Until(t.expiry) > OAUTH2_EXPIRY_DELTA)
+}
+
+func (t *oauth2Token) authorization() string {
+	if t.tokenType == "" || strings.EqualFold(t.tokenType, "bearer") {
+		return "Bearer " + t.accessToken
+	}
+	return t.tokenType + " " + t.accessToken
+}
+
+// requestAuth returns the named auth of the config, nil if name is empty
+func (a *App) requestAuth(name string) (*requestAuth, error) {
+	if name == "" {
+		return nil, nil
+	}
+	auth, found := a.config.Auth[name]
+	if !found {
+		return nil, fmt.Errorf("Unknown auth: %v", name)
+	}
+	auth.Type = strings.ToLower(auth.Type)
+	switch auth.Type {
+	case AUTH_BASIC, AUTH_BEARER, AUTH_DIGEST, AUTH_OAUTH2:
+	default:
+		return nil, fmt.Errorf("Unknown auth type of %v: %v (values: %v, %v, %v, %v)", name, auth.Type, AUTH_BASIC, AUTH_BEARER, AUTH_DIGEST, AUTH_OAUTH2)
+	}
+	for _, value := range []*string{&auth.Username, &auth.Password, &auth.Token, &auth.TokenURL, &auth.ClientID, &auth.ClientSecret, &auth.RefreshToken} {
+		*value = a.substituteVariables(*value)
+	}
+	scopes := make([]string, len(auth.Scopes))
+	for i, scope := range auth.Scopes {
+		scopes[i] = a.substituteVariables(scope)
+	}
+	auth.Scopes = scopes
+	return &requestAuth{name, auth}, nil
+}
+
+// authClient sends requests with an auth and a signing
+type authClient struct {
+	app    *App
+	client *http.Client
+	auth   *requestAuth
+	signer *requestSigner
+}
+
+func (c authClient) Do(req *http.Request) (*http.Response, error) {
+	return c.app.sendWithAuth(c.client, req, c.auth, c.signer)
+}
+
+// sendWithAuth sends a request with the credentials of auth, unless the
+// request has an Authorization header. A 401 response is answered with
+// the digest of its challenge or with a renewed OAuth2 token. Every sent
+// request is signed by signer.
+func (a *App) sendWithAuth(client *http.Client, req *http.Request, auth *requestAuth, signer *requestSigner) (*http.Response, error) {
+	send := func(req *http.Request) (*http.Response, error) {
+		if signer != nil {
+			if err := signer.sign(req); err != nil {
+				return nil, fmt.Errorf("Signing error: %v", err)
+			}
+		}
+		return client.Do(req)
+	}
+	if auth == nil || req.Header.Get("Authorization") != "" {
+		return send(req)
+	}
+	renewToken := false
+	switch auth.Type {
+	case AUTH_BASIC:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case AUTH_BEARER:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case AUTH_OAUTH2:
+		token, cached, err := a.oauth2Token(req.Context(), auth, false)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", token.authorization())
+		renewToken = cached
+	}
+
+	response, err := send(req)
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+	if auth.Type != AUTH_DIGEST && !renewToken {
+		return response, nil
+	}
+	// bodies of readers without GetBody cannot be sent again
+	if req.GetBody == nil && req.Body != nil && req.Body != http.NoBody {
+		return response, nil
+	}
+
+	var authorization string
+	switch auth.Type {
+	case AUTH_DIGEST:
+		challenge := findDigestChallenge(response.Header)
+		if challenge == nil {
+			return response, nil
+		}
+		var body []byte
+		if req.GetBody != nil {
+			if body, err = readRequestBody(req); err != nil {
+				response.Body.Close()
+				return nil, err
+			}
+		}
+		authorization, err = challenge.authorization(auth.Username, auth.Password, req.Method, req.URL.RequestURI(), body)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+	case AUTH_OAUTH2:
+		token, _, err := a.oauth2Token(req.Context(), auth, true)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+		authorization = token.authorization()
+	}
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+	response.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", authorization)
+	return send(retry)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       []string
+}
+
+// findDigestChallenge returns the first Digest challenge of the
+// WWW-Authenticate headers
+func findDigestChallenge(header http.Header) *digestChallenge {
+	for _, value := range header.Values("WWW-Authenticate") {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "digest") {
+			continue
+		}
+		p := parseAuthParams(params)
+		c := &digestChallenge{
+			realm:     p["realm"],
+			nonce:     p["nonce"],
+			opaque:    p["opaque"],
+			algorithm: p["algorithm"],
+		}
+		for _, qop := range strings.Split(p["qop"], ",") {
+			if qop = strings.TrimSpace(qop); qop != "" {
+				c.qop = append(c.qop, qop)
+			}
+		}
+		return c
+	}
+	return nil
+}
+
+// parseAuthParams parses the comma separated key=value parameters of a
+// challenge, values can be quoted strings
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, ", \t") {
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimSpace(s[i+1:])
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			j := 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				value.WriteByte(s[j])
+			}
+			s = s[min(j+1, len(s)):]
+		} else {
+			j := strings.IndexByte(s, ',')
+			if j < 0 {
+				j = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:j]))
+			s = s[j:]
+		}
+		params[key] = value.String()
+	}
+	return params
+}
+
+// authorization computes the Authorization header of RFC 7616
+func (c *digestChallenge) authorization(username, password, method, uri string, body []byte) (string, error) {
+	var newHash func() hash.Hash
+	algorithm := strings.ToUpper(c.algorithm)
+	switch strings.TrimSuffix(algorithm, "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("Unsupported digest algorithm: %v", c.algorithm)
+	}
+	h := func(values ...string) string {
+		d := newHash()
+		io.WriteString(d, strings.Join(values, ":"))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(nonce)
+	nc := "00000001"
+
+	ha1 := h(username, c.realm, password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+	qop := ""
+	for _, q := range c.qop {
+		if q == "auth" || (q == "auth-int" && qop == "") {
+			qop = q
+		}
+	}
+	ha2 := h(method, uri)
+	if qop == "auth-int" {
+		ha2 = h(method, uri, h(string(body)))
+	}
+	var response string
+	if qop == "" {
+		response = h(ha1, c.nonce, ha2)
+	} else {
+		response = h(ha1, c.nonce, nc, cnonce, qop, ha2)
+	}
+
+	quote := strconv.Quote
+	params := []string{
+		"username=" + quote(username),
+		"realm=" + quote(c.realm),
+		"nonce=" + quote(c.nonce),
+		"uri=" + quote(uri),
+		"response=" + quote(response),
+	}
+	if c.algorithm != "" {
+		params = append(params, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		params = append(params, "opaque="+quote(c.opaque))
+	}
+	if qop != "" {
+		params = append(params, "qop="+qop, "nc="+nc, "cnonce="+quote(cnonce))
+	}
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// oauth2Token returns the cached token of auth or requests a new one with
+// the refresh token or client credentials grant. cached is true if the
+// token was not requested by this call.
+func (a *App) oauth2Token(ctx context.Context, auth *requestAuth, renew bool) (token *oauth2Token, cached bool, err error) {
+	if auth.TokenURL == "" {
+		return nil, false, fmt.Errorf("No token URL specified for %v", auth.name)
+	}
+	a.oauth2.Lock()
+	defer a.oauth2.Unlock()
+	key := strings.Join([]string{auth.name, auth.TokenURL, auth.ClientID}, "\n")
+	token = a.oauth2.tokens[key]
+	if !renew && token.valid() {
+		return token, true, nil
+	}
+
+	form := url.Values{}
+	refreshToken := auth.RefreshToken
+	if token != nil && token.refreshToken != "" {
+		refreshToken = token.refreshToken
+	}
+	if refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+	if timeout := a.config.General.Timeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	token, err = requestOAuth2Token(ctx, auth, form)
+	if err != nil {
+		delete(a.oauth2.tokens, key)
+		return nil, false, err
+	}
+	if token.refreshToken == "" {
+		// the refresh token can be used again
+		token.refreshToken = refreshToken
+	}
+	if a.oauth2.tokens == nil {
+		a.oauth2.tokens = make(map[string]*oauth2Token)
+	}
+	a.oauth2.tokens[key] = token
+	return token, false, nil
+}
+
+func requestOAuth2Token(ctx context.Context, auth *requestAuth, form url.Values) (*oauth2Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", config.ContentTypes["form"])
+	req.Header.Set("Accept", config.ContentTypes["json"])
+	if auth.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+	}
+	response, err := CLIENT.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Token request failed: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024*1024))
+	if err != nil {
+		return nil, fmt.Errorf("Token request failed: %v", err)
+	}
+
+	var result struct {
+		AccessToken      string          `json:"access_token"`
+		TokenType        string          `json:"token_type"`
+		RefreshToken     string          `json:"refresh_token"`
+		ExpiresIn        json.RawMessage `json:"expires_in"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.AccessToken == "" {
+		message := result.Error
+		if result.ErrorDescription != "" {
+			message += ": " + result.ErrorDescription
+		}
+		if message == "" {
+			message = "no access token"
+		}
+		return nil, fmt.Errorf("Token request failed: %v: %v", response.Status, message)
+	}
+	token := &oauth2Token{
+		accessToken:  result.AccessToken,
+		tokenType:    result.TokenType,
+		refreshToken: result.RefreshToken,
+	}
+	// some servers send the lifetime as a string
+	if expiresIn, err := strconv.Atoi(strings.Trim(string(result.ExpiresIn), `"`)); err == nil && expiresIn > 0 {
+		token.expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+func (a *App) authNames() []string {
+	names := make([]string, 0, len(a.config.Auth))
+	for name := range a.config.Auth {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToggleAuth lists the configured auths, the selected auth is used by the
+// following requests
+func (a *App) ToggleAuth(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == AUTH_VIEW {
+		a.closePopup(g, AUTH_VIEW)
+		return nil
+	}
+	names := a.authNames()
+	if len(names) == 0 {
+		return a.OpenSaveResultView("No auth configured, add [auth.NAME] sections to the config", g)
+	}
+	return a.openSelectionList(g, AUTH_VIEW, names, a.config.General.Auth, func(name string) string {
+		return strings.ToLower(a.config.Auth[name].Type)
+	})
+}
+
+func (a *App) selectAuth(g *gocui.Gui, v *gocui.View) error {
+	idx := viewCursorLine(g, AUTH_VIEW)
+	if idx >= len(a.selectionList) {
+		return nil
+	}
+	a.config.General.Auth = a.selectionList[idx]
+	a.closePopup(g, AUTH_VIEW)
+	refreshStatusLine(a, g)
+	return nil
+}
+
+// openSelectionList lists named config entries with their types, the
+// first entry selects none of them
+func (a *App) openSelectionList(g *gocui.Gui, viewName string, names []string, current string, entryType func(name string) string) error {
+	a.selectionList = append([]string{""}, names...)
+	list, err := a.CreatePopupView(viewName, 60, len(a.selectionList), g)
+	if err != nil {
+		return err
+	}
+	list.Title = VIEW_TITLES[viewName]
+	for i, name := range a.selectionList {
+		if name == "" {
+			fmt.Fprintln(list, "none")
+		} else {
+			fmt.Fprintf(list, "%v (%v)\n", name, entryType(name))
+		}
+		if name == current {
+			list.SetCursor(0, i)
+		}
+	}
+	g.SetViewOnTop(viewName)
+	g.SetCurrentView(viewName)
+	return nil
+}
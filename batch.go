@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/asciimoo/wuzz/config"
+	"github.com/asciimoo/wuzz/request"
+)
+
+// exit codes of the batch mode, 3xx, 4xx and 5xx responses exit with their
+// status class
+const (
+	BATCH_EXIT_OK               = 0
+	BATCH_EXIT_REQUEST_FAILED   = 1
+	BATCH_EXIT_INVALID_ARGS     = 2
+	BATCH_EXIT_INFORMATIONAL    = 6
+	BATCH_EXIT_ASSERTION_FAILED = 7
+)
+
+// runBatch sends the request of the command line arguments without the
+// TUI and prints the response body. It returns the exit code of the batch
+// mode.
+func runBatch(configPath string, args []string) int {
+	a := &App{}
+	if err := a.LoadConfig(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return BATCH_EXIT_INVALID_ARGS
+	}
+	r := &Request{
+		Url:    fmt.Sprintf("%s://", a.config.General.DefaultURLScheme),
+		Method: http.MethodGet,
+	}
+	raw := false
+	for _, arg := range args {
+		if arg == "--raw" {
+			raw = true
+		}
+	}
+	err := a.parseArgs(r, args)
+	if err == nil && r.Url == fmt.Sprintf("%s://", a.config.General.DefaultURLScheme) {
+		err = errors.New("No URL specified")
+	}
+	if err == nil {
+		err = a.InitConfig()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error!", err)
+		return BATCH_EXIT_INVALID_ARGS
+	}
+	r.Auth = a.config.General.Auth
+	r.Signing = a.config.General.Signing
+	r.Assertions = a.assertions
+	r.Captures = a.captures
+
+	err = a.sendRequest(r)
+	a.SaveCookies()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error!", err)
+		return BATCH_EXIT_REQUEST_FAILED
+	}
+	if raw {
+		os.Stdout.Write(r.RawResponseBody)
+	} else if err := a.responseFormatter(r).Format(os.Stdout, r.RawResponseBody); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot decode response body: %v\n", err)
+		os.Stdout.Write(r.RawResponseBody)
+	}
+	for _, result := range r.AssertionResults {
+		if !result.Passed {
+			fmt.Fprintf(os.Stderr, "FAIL %v: %v\n", result.Assertion, result.Message)
+		}
+	}
+	return batchExitCode(r)
+}
+
+// batchExitCode returns the exit code of a received response. Failed
+// assertions have precedence over the status.
+func batchExitCode(r *Request) int {
+	for _, result := range r.AssertionResults {
+		if !result.Passed {
+			return BATCH_EXIT_ASSERTION_FAILED
+		}
+	}
+	switch class := r.StatusCode / 100; class {
+	case 2:
+		return BATCH_EXIT_OK
+	case 3, 4, 5:
+		return class
+	}
+	return BATCH_EXIT_INFORMATIONAL
+}
+
+// sendRequest sends a request without the views, reads the whole
+// response, checks its assertions and captures its variables
+func (a *App) sendRequest(r *Request) error {
+	if u := a.substituteVariables(r.Url); isWebSocketURL(u) || isGRPCURL(u) {
+		return errors.New("WebSocket and gRPC requests are not supported without the TUI")
+	}
+	model := &request.Request{
+		Method:  r.Method,
+		URL:     r.Url,
+		Params:  r.GetParams,
+		Headers: r.Headers,
+		Data:    r.Data,
+	}
+	if r.GraphQL {
+		r.Method = http.MethodPost
+		r.OperationName = graphQLOperationName(r.Data, 0)
+		data, err := graphQLBody(a.substituteVariables(r.Data), a.substituteVariables(r.Variables), r.OperationName)
+		if err != nil {
+			return fmt.Errorf("GraphQL error: %v", err)
+		}
+		model.Method = r.Method
+		model.Body = data
+		if !hasHeader(r.Headers, "Content-Type") {
+			model.Headers += "\nContent-Type: " + config.ContentTypes["json"]
+		}
+	}
+	auth, err := a.requestAuth(r.Auth)
+	if err != nil {
+		return fmt.Errorf("Auth error: %v", err)
+	}
+	signer, err := a.requestSigner(r.Signing)
+	if err != nil {
+		return fmt.Errorf("Signing error: %v", err)
+	}
+	client := &request.Client{
+		Builder:    a.requestBuilder(),
+		HTTPClient: authClient{a, CLIENT, auth, signer},
+	}
+
+	ctx := context.Background()
+	if timeout := a.config.General.Timeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	r.Timestamp = time.Now()
+	response, err := client.Send(ctx, model)
+	if err != nil {
+		return err
+	}
+	r.StatusCode = response.StatusCode
+	r.Proto = response.Proto
+	r.RawResponseHeaders = response.Header
+	r.RawResponseBody = response.Body
+	r.ContentType = response.Header.Get("Content-Type")
+	r.Duration = response.Duration
+	r.SentMethod = response.Request.Method
+	r.SentURL = response.Request.URL.String()
+	r.SentHeaders = response.Request.Header.Clone()
+	r.AssertionResults = evaluateAssertions(r)
+	a.captureVariables(r)
+	return nil
+}
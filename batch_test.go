@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestBatchExitCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		passed     []bool
+		code       int
+	}{
+		{200, nil, BATCH_EXIT_OK},
+		{204, []bool{true}, BATCH_EXIT_OK},
+		{101, nil, BATCH_EXIT_INFORMATIONAL},
+		{301, nil, 3},
+		{404, nil, 4},
+		{503, nil, 5},
+		{404, []bool{true}, 4},
+		{200, []bool{true, false}, BATCH_EXIT_ASSERTION_FAILED},
+		{500, []bool{false}, BATCH_EXIT_ASSERTION_FAILED},
+	}
+	for _, test := range tests {
+		r := &Request{StatusCode: test.statusCode}
+		for _, passed := range test.passed {
+			r.AssertionResults = append(r.AssertionResults, AssertionResult{Passed: passed})
+		}
+		if code := batchExitCode(r); code != test.code {
+			t.Errorf("Expected exit code %d for %d %v, got %d", test.code, test.statusCode, test.passed, code)
+		}
+	}
+}
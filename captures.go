@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/tidwall/gjson"
+)
+
+var CAPTURE_SOURCES = []string{"json", "header", "regex"}
+
+// variableNamePattern matches the names usable as {{name}} placeholders
+var variableNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// CaptureResult is a value of a response captured into a variable
+type CaptureResult struct {
+	Name    string
+	Value   string `json:",omitempty"`
+	Message string `json:",omitempty"`
+}
+
+// capture stores a part of a response in a session variable, e.g.
+//
+//	token = json data.token
+//	session = header X-Session-Id
+//	id = regex "id":\s*(\d+)
+//
+// A regex captures its first group or the whole match if it has no groups.
+type capture struct {
+	name    string
+	source  string
+	arg     string
+	pattern *regexp.Regexp
+}
+
+func parseCapture(line string) (*capture, error) {
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("No variable name specified")
+	}
+	c := &capture{name: strings.TrimSpace(parts[0])}
+	if !variableNamePattern.MatchString(c.name) {
+		return nil, fmt.Errorf("Invalid variable name: %v", c.name)
+	}
+	rest := strings.TrimSpace(parts[1])
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		c.source, c.arg = strings.ToLower(rest[:i]), strings.TrimSpace(rest[i:])
+	} else {
+		c.source = strings.ToLower(rest)
+	}
+	if c.source == "" {
+		return nil, errors.New("No capture source specified")
+	}
+	if !slices.Contains(CAPTURE_SOURCES, c.source) {
+		return nil, fmt.Errorf("Unknown capture source: %v", c.source)
+	}
+	if strings.HasPrefix(c.arg, `"`) {
+		if arg, err := strconv.Unquote(c.arg); err == nil {
+			c.arg = arg
+		}
+	}
+	if c.arg == "" {
+		return nil, fmt.Errorf("No %v specified", map[string]string{
+			"json":   "JSON path",
+			"header": "header name",
+			"regex":  "regular expression",
+		}[c.source])
+	}
+	if c.source == "regex" {
+		var err error
+		if c.pattern, err = regexp.Compile(c.arg); err != nil {
+			return nil, fmt.Errorf("Invalid regular expression: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// extract returns the captured value of a response
+func (c *capture) extract(r *Request) (string, error) {
+	switch c.source {
+	case "json":
+		result := gjson.GetBytes(r.RawResponseBody, c.arg)
+		if !result.Exists() {
+			return "", errors.New("not found")
+		}
+		return result.String(), nil
+	case "header":
+		if values := r.RawResponseHeaders.Values(c.arg); len(values) > 0 {
+			return values[0], nil
+		}
+		return "", errors.New("not found")
+	case "regex":
+		match := c.pattern.FindSubmatch(r.RawResponseBody)
+		if match == nil {
+			return "", errors.New("no match in the body")
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+	return "", fmt.Errorf("Unknown capture source: %v", c.source)
+}
+
+// evaluateCaptures extracts the captures of a request, one per line,
+// lines starting with # are comments
+func evaluateCaptures(r *Request) []CaptureResult {
+	var results []CaptureResult
+	for _, line := range strings.Split(r.Captures, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c, err := parseCapture(line)
+		if err != nil {
+			results = append(results, CaptureResult{Name: line, Message: err.Error()})
+			continue
+		}
+		result := CaptureResult{Name: c.name}
+		if result.Value, err = c.extract(r); err != nil {
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// sessionVariables are the captured variables, they are written by the UI
+// goroutine and read by the goroutines sending requests
+type sessionVariables struct {
+	sync.RWMutex
+	values map[string]string
+}
+
+func (s *sessionVariables) get(name string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	value, found := s.values[name]
+	return value, found
+}
+
+func (s *sessionVariables) set(name, value string) {
+	s.Lock()
+	defer s.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[name] = value
+}
+
+func (s *sessionVariables) clear() {
+	s.Lock()
+	defer s.Unlock()
+	s.values = nil
+}
+
+func (s *sessionVariables) len() int {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.values)
+}
+
+// list returns the names and values of the variables sorted by name
+func (s *sessionVariables) list() (names, values []string) {
+	s.RLock()
+	defer s.RUnlock()
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values = append(values, s.values[name])
+	}
+	return names, values
+}
+
+// captureVariables stores the captured values of a response in the session
+// variables used by the following requests. Variables which were not
+// captured keep their previous value.
+func (a *App) captureVariables(r *Request) {
+	r.CaptureResults = evaluateCaptures(r)
+	for _, result := range r.CaptureResults {
+		if result.Message == "" {
+			a.sessionVariables.set(result.Name, result.Value)
+		}
+	}
+}
+
+// ToggleCaptures edits the captures of the responses of the following
+// requests
+func (a *App) ToggleCaptures(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == CAPTURES_VIEW {
+		a.closePopup(g, CAPTURES_VIEW)
+		return nil
+	}
+	editor, err := a.CreatePopupView(CAPTURES_VIEW, 80, 12, g)
+	if err != nil {
+		return err
+	}
+	g.Cursor = true
+	editor.Title = VIEW_TITLES[CAPTURES_VIEW]
+	editor.Editable = true
+	editor.Highlight = false
+	fmt.Fprint(editor, a.captures)
+	g.SetViewOnTop(CAPTURES_VIEW)
+	g.SetCurrentView(CAPTURES_VIEW)
+	return nil
+}
+
+func (a *App) saveCaptures(g *gocui.Gui, v *gocui.View) error {
+	a.captures = strings.TrimSpace(v.Buffer())
+	a.closePopup(g, CAPTURES_VIEW)
+	for i, line := range strings.Split(a.captures, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := parseCapture(line); err != nil {
+			return a.OpenSaveResultView(fmt.Sprintf("Invalid capture in line %d: %v", i+1, err), g)
+		}
+	}
+	return nil
+}
+
+// ToggleSessionVariables shows the captured session variables and the
+// captures of the selected request
+func (a *App) ToggleSessionVariables(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == SESSION_VARIABLES_VIEW {
+		a.closePopup(g, SESSION_VARIABLES_VIEW)
+		return nil
+	}
+	maxX, maxY := g.Size()
+	variables, err := a.CreatePopupView(SESSION_VARIABLES_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	variables.Title = VIEW_TITLES[SESSION_VARIABLES_VIEW]
+	variables.Highlight = false
+	variables.Wrap = true
+	g.SetViewOnTop(SESSION_VARIABLES_VIEW)
+	g.SetCurrentView(SESSION_VARIABLES_VIEW)
+	a.writeSessionVariables(variables)
+	return nil
+}
+
+func (a *App) writeSessionVariables(v *gocui.View) {
+	v.Clear()
+	names, values := a.sessionVariables.list()
+	if len(names) == 0 {
+		fmt.Fprintln(v, "[!] No variables captured")
+	}
+	for i, name := range names {
+		fmt.Fprintf(v, "\x1b[0;33m%v:\x1b[0;0m %v\n", name, values[i])
+	}
+	if len(a.history) == 0 || len(a.history[a.historyIndex].CaptureResults) == 0 {
+		return
+	}
+	fmt.Fprintln(v, "\nCaptures of the request:")
+	for _, result := range a.history[a.historyIndex].CaptureResults {
+		if result.Message == "" {
+			fmt.Fprintf(v, "\x1b[0;32mSET\x1b[0;0m  %v\n", result.Name)
+		} else {
+			fmt.Fprintf(v, "\x1b[0;31mMISS\x1b[0;0m %v: %v\n", result.Name, result.Message)
+		}
+	}
+}
+
+// clearSessionVariables forgets the captured session variables
+func (a *App) clearSessionVariables(g *gocui.Gui, v *gocui.View) error {
+	a.sessionVariables.clear()
+	a.writeSessionVariables(v)
+	refreshStatusLine(a, g)
+	return nil
+}
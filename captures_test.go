@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestParseCapture(t *testing.T) {
+	tests := []struct {
+		line              string
+		name, source, arg string
+		err               string
+	}{
+		{"token = json data.token", "token", "json", "data.token", ""},
+		{"session=HEADER\tX-Session-Id", "session", "header", "X-Session-Id", ""},
+		{`id = regex "id":\s*(\d+)`, "id", "regex", `"id":\s*(\d+)`, ""},
+		{`name = json "data.full name"`, "name", "json", "data.full name", ""},
+		{"a.b-c_1 = json x", "a.b-c_1", "json", "x", ""},
+		{"token json data.token", "", "", "", "No variable name specified"},
+		{" = json data.token", "", "", "", "Invalid variable name: "},
+		{"my token = json data.token", "", "", "", "Invalid variable name: my token"},
+		{"{{token}} = json data.token", "", "", "", "Invalid variable name: {{token}}"},
+		{"token =", "", "", "", "No capture source specified"},
+		{"token = cookie session", "", "", "", "Unknown capture source: cookie"},
+		{"token = json", "", "", "", "No JSON path specified"},
+		{"token = header ", "", "", "", "No header name specified"},
+		{"token = regex", "", "", "", "No regular expression specified"},
+		{"token = regex (", "", "", "", "Invalid regular expression: error parsing regexp: missing closing ): `(`"},
+	}
+	for _, test := range tests {
+		c, err := parseCapture(test.line)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Expected error %q for %q, got %v", test.err, test.line, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.line, err)
+			continue
+		}
+		if c.name != test.name || c.source != test.source || c.arg != test.arg {
+			t.Errorf("Unexpected capture for %q: %+v", test.line, c)
+		}
+		if (c.source == "regex") != (c.pattern != nil) {
+			t.Errorf("Unexpected pattern for %q: %v", test.line, c.pattern)
+		}
+	}
+}
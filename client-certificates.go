@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http/httptrace"
+	"strings"
+
+	"github.com/asciimoo/wuzz/config"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mitchellh/go-homedir"
+)
+
+// passphraseRequiredError is returned if an encrypted client key is
+// configured without a passphrase. Host is empty for the default client
+// certificate.
+type passphraseRequiredError struct {
+	Host    string
+	KeyFile string
+	Invalid bool
+}
+
+func (e *passphraseRequiredError) Error() string {
+	if e.Invalid {
+		return fmt.Sprintf("Invalid passphrase for %v", e.KeyFile)
+	}
+	return fmt.Sprintf("Passphrase required for %v", e.KeyFile)
+}
+
+// clientCertificateTarget holds the host of the connection being
+// established by a request, so the matching per-host client certificate
+// can be presented
+type clientCertificateTarget struct {
+	host string
+}
+
+type clientCertificateTargetKey struct{}
+
+// withClientCertificateTarget tracks the target host of every connection
+// of the request, including redirects
+func withClientCertificateTarget(ctx context.Context) context.Context {
+	target := &clientCertificateTarget{}
+	ctx = context.WithValue(ctx, clientCertificateTargetKey{}, target)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			target.host = hostPort
+		},
+	})
+}
+
+func readFile(file string) ([]byte, error) {
+	location, err := homedir.Expand(file)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(location)
+}
+
+func loadClientCertificate(host string, cert config.ClientCertificate) (*tls.Certificate, error) {
+	certPEM, err := readFile(cert.Cert)
+	if err != nil {
+		return nil, err
+	}
+	// the key can be stored in the certificate file
+	keyFile := cert.Key
+	if keyFile == "" {
+		keyFile = cert.Cert
+	}
+	keyPEM, err := readFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err = decryptPEMKey(keyPEM, cert.Passphrase)
+	if err == errPassphraseRequired || err == errInvalidPassphrase {
+		return nil, &passphraseRequiredError{host, keyFile, err == errInvalidPassphrase}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", keyFile, err)
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &certificate, nil
+}
+
+var (
+	errPassphraseRequired = errors.New("Passphrase required")
+	errInvalidPassphrase  = errors.New("Invalid passphrase")
+)
+
+// decryptPEMKey returns the first private key of the PEM data, decrypted
+// if required
+func decryptPEMKey(data []byte, passphrase string) ([]byte, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("No private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			return nil, errors.New("Encrypted PKCS#8 keys are not supported, convert the key with `openssl pkey -traditional`")
+		}
+		if !x509.IsEncryptedPEMBlock(block) {
+			return pem.EncodeToMemory(block), nil
+		}
+		if passphrase == "" {
+			return nil, errPassphraseRequired
+		}
+		der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return nil, errInvalidPassphrase
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+	}
+}
+
+func loadCACertificates(file string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	data, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("No certificates found in %v", file)
+	}
+	return pool, nil
+}
+
+// initClientCertificates configures the CA bundle and the client
+// certificates presented to the servers
+func (a *App) initClientCertificates(tlsConfig *tls.Config) error {
+	if a.config.General.CACert != "" {
+		pool, err := loadCACertificates(a.config.General.CACert)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	var defaultCertificate *tls.Certificate
+	if a.config.General.ClientCert != "" {
+		var err error
+		defaultCertificate, err = loadClientCertificate("", config.ClientCertificate{
+			Cert:       a.config.General.ClientCert,
+			Key:        a.config.General.ClientKey,
+			Passphrase: a.config.General.ClientKeyPassphrase,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	hostCertificates := make(map[string]*tls.Certificate, len(a.config.ClientCertificates))
+	for host, cert := range a.config.ClientCertificates {
+		certificate, err := loadClientCertificate(host, cert)
+		if err != nil {
+			return err
+		}
+		hostCertificates[host] = certificate
+	}
+	if defaultCertificate == nil && len(hostCertificates) == 0 {
+		return nil
+	}
+
+	tlsConfig.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if target, ok := cri.Context().Value(clientCertificateTargetKey{}).(*clientCertificateTarget); ok {
+			// host:port has precedence over host
+			if certificate, found := hostCertificates[target.host]; found {
+				return certificate, nil
+			}
+			host, _, err := net.SplitHostPort(target.host)
+			if err == nil {
+				if certificate, found := hostCertificates[host]; found {
+					return certificate, nil
+				}
+			}
+		}
+		if defaultCertificate != nil {
+			return defaultCertificate, nil
+		}
+		// no certificate is sent
+		return &tls.Certificate{}, nil
+	}
+	return nil
+}
+
+// OpenPassphraseDialog asks for the passphrase of an encrypted client key
+// and reapplies the configuration
+func (a *App) OpenPassphraseDialog(g *gocui.Gui, e *passphraseRequiredError) error {
+	err := a.OpenDialog(e.Error()+" (enter to submit)", "", g,
+		func(g *gocui.Gui, _ *gocui.View) error {
+			passphrase := getViewValue(g, SAVE_DIALOG_VIEW)
+			a.closePopup(g, SAVE_DIALOG_VIEW)
+			if e.Host == "" {
+				a.config.General.ClientKeyPassphrase = passphrase
+			} else {
+				cert := a.config.ClientCertificates[e.Host]
+				cert.Passphrase = passphrase
+				a.config.ClientCertificates[e.Host] = cert
+			}
+			return a.applyConfig(g)
+		})
+	if dialog, viewErr := g.View(SAVE_DIALOG_VIEW); viewErr == nil {
+		dialog.Mask = '*'
+	}
+	return err
+}
+
+// applyConfig runs InitConfig and reports errors in the TUI
+func (a *App) applyConfig(g *gocui.Gui) error {
+	err := a.InitConfig()
+	if err == nil {
+		return nil
+	}
+	if passphraseErr, ok := err.(*passphraseRequiredError); ok {
+		return a.OpenPassphraseDialog(g, passphraseErr)
+	}
+	return a.OpenSaveResultView("Configuration error: "+err.Error(), g)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mitchellh/go-homedir"
+)
+
+const COLLECTION_EXTENSION = ".json"
+
+type collectionEntry struct {
+	// path relative to the collections directory
+	path  string
+	depth int
+	isDir bool
+}
+
+func (a *App) collectionsDir() string {
+	if a.config.General.CollectionsDir != "" {
+		dir, err := homedir.Expand(a.config.General.CollectionsDir)
+		if err == nil {
+			return dir
+		}
+		return a.config.General.CollectionsDir
+	}
+	return filepath.Join(a.configDir, "collections")
+}
+
+func (a *App) collectionPath(relPath string) string {
+	return filepath.Join(a.collectionsDir(), relPath)
+}
+
+// readCollectionTree lists the folders and saved requests of the
+// collections directory, skipping the content of collapsed folders
+func (a *App) readCollectionTree(relDir string, depth int) []collectionEntry {
+	files, err := ioutil.ReadDir(a.collectionPath(relDir))
+	if err != nil {
+		return nil
+	}
+	entries := make([]collectionEntry, 0, len(files))
+	for _, isDir := range []bool{true, false} {
+		for _, f := range files {
+			if f.IsDir() != isDir {
+				continue
+			}
+			relPath := filepath.Join(relDir, f.Name())
+			if !isDir {
+				if filepath.Ext(f.Name()) == COLLECTION_EXTENSION {
+					entries = append(entries, collectionEntry{relPath, depth, false})
+				}
+				continue
+			}
+			entries = append(entries, collectionEntry{relPath, depth, true})
+			if !a.collapsedFolders[relPath] {
+				entries = append(entries, a.readCollectionTree(relPath, depth+1)...)
+			}
+		}
+	}
+	return entries
+}
+
+func (a *App) ToggleCollections(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == COLLECTIONS_VIEW {
+		a.closePopup(g, COLLECTIONS_VIEW)
+		return nil
+	}
+	return a.showCollections(g, 0)
+}
+
+func (a *App) showCollections(g *gocui.Gui, cursor int) error {
+	if a.collapsedFolders == nil {
+		a.collapsedFolders = make(map[string]bool)
+	}
+	a.collectionTree = a.readCollectionTree("", 0)
+
+	collections, err := a.CreatePopupView(COLLECTIONS_VIEW, 100, len(a.collectionTree), g)
+	if err != nil {
+		return err
+	}
+	collections.Title = VIEW_TITLES[COLLECTIONS_VIEW]
+	g.SetViewOnTop(COLLECTIONS_VIEW)
+	g.SetCurrentView(COLLECTIONS_VIEW)
+
+	if len(a.collectionTree) == 0 {
+		setViewTextAndCursor(collections, "[!] No saved requests in "+a.collectionsDir())
+		return nil
+	}
+	for _, e := range a.collectionTree {
+		indent := strings.Repeat("  ", e.depth)
+		name := filepath.Base(e.path)
+		if !e.isDir {
+			fmt.Fprintf(collections, "%s  %s\n", indent, strings.TrimSuffix(name, COLLECTION_EXTENSION))
+			continue
+		}
+		marker := "-"
+		if a.collapsedFolders[e.path] {
+			marker = "+"
+		}
+		fmt.Fprintf(collections, "%s%s %s/\n", indent, marker, name)
+	}
+	if cursor >= len(a.collectionTree) {
+		cursor = len(a.collectionTree) - 1
+	}
+	collections.SetCursor(0, cursor)
+	return nil
+}
+
+func (a *App) selectedCollectionEntry(v *gocui.View) (int, *collectionEntry) {
+	_, cy := v.Cursor()
+	_, oy := v.Origin()
+	idx := cy + oy
+	if idx < 0 || idx >= len(a.collectionTree) {
+		return idx, nil
+	}
+	return idx, &a.collectionTree[idx]
+}
+
+func (a *App) openCollectionEntry(g *gocui.Gui, v *gocui.View) error {
+	idx, e := a.selectedCollectionEntry(v)
+	if e == nil {
+		return nil
+	}
+	if e.isDir {
+		a.currentCollection = e.path
+		a.collapsedFolders[e.path] = !a.collapsedFolders[e.path]
+		return a.showCollections(g, idx)
+	}
+	a.currentCollection = filepath.Dir(e.path)
+	a.closePopup(g, COLLECTIONS_VIEW)
+	return a.LoadRequest(g, a.collectionPath(e.path))
+}
+
+func (a *App) newCollectionFolder(g *gocui.Gui, v *gocui.View) error {
+	idx, e := a.selectedCollectionEntry(v)
+	parent := a.currentCollection
+	if e != nil {
+		parent = filepath.Dir(e.path)
+		if e.isDir {
+			parent = e.path
+		}
+	}
+	if parent == "." {
+		parent = ""
+	} else if parent != "" {
+		parent += string(filepath.Separator)
+	}
+	return a.OpenDialog("New folder (enter to submit, ctrl+q to cancel)", parent, g,
+		func(g *gocui.Gui, _ *gocui.View) error {
+			defer a.closePopup(g, SAVE_DIALOG_VIEW)
+			name := getViewValue(g, SAVE_DIALOG_VIEW)
+			if name == "" {
+				return a.showCollections(g, idx)
+			}
+			if err := os.MkdirAll(a.collectionPath(name), 0755); err != nil {
+				return a.OpenSaveResultView("Error creating folder: "+err.Error(), g)
+			}
+			a.currentCollection = filepath.Clean(name)
+			return a.showCollections(g, idx)
+		})
+}
+
+func (a *App) renameCollectionEntry(g *gocui.Gui, v *gocui.View) error {
+	idx, e := a.selectedCollectionEntry(v)
+	if e == nil {
+		return nil
+	}
+	entry := *e
+	return a.OpenDialog("Rename (enter to submit, ctrl+q to cancel)", strings.TrimSuffix(entry.path, COLLECTION_EXTENSION), g,
+		func(g *gocui.Gui, _ *gocui.View) error {
+			defer a.closePopup(g, SAVE_DIALOG_VIEW)
+			newPath := getViewValue(g, SAVE_DIALOG_VIEW)
+			if newPath == "" {
+				return a.showCollections(g, idx)
+			}
+			if !entry.isDir && filepath.Ext(newPath) != COLLECTION_EXTENSION {
+				newPath += COLLECTION_EXTENSION
+			}
+			newLocation := a.collectionPath(newPath)
+			if _, err := os.Stat(newLocation); err == nil {
+				return a.OpenSaveResultView("Error renaming: "+newPath+" already exists", g)
+			}
+			err := os.MkdirAll(filepath.Dir(newLocation), 0755)
+			if err == nil {
+				err = os.Rename(a.collectionPath(entry.path), newLocation)
+			}
+			if err != nil {
+				return a.OpenSaveResultView("Error renaming: "+err.Error(), g)
+			}
+			if entry.isDir {
+				delete(a.collapsedFolders, entry.path)
+			}
+			return a.showCollections(g, idx)
+		})
+}
+
+func (a *App) duplicateCollectionEntry(g *gocui.Gui, v *gocui.View) error {
+	idx, e := a.selectedCollectionEntry(v)
+	if e == nil || e.isDir {
+		return nil
+	}
+	data, err := ioutil.ReadFile(a.collectionPath(e.path))
+	if err != nil {
+		return a.OpenSaveResultView("Error duplicating request: "+err.Error(), g)
+	}
+	base := strings.TrimSuffix(e.path, COLLECTION_EXTENSION) + " copy"
+	newPath := base + COLLECTION_EXTENSION
+	for i := 2; ; i++ {
+		if _, err := os.Stat(a.collectionPath(newPath)); os.IsNotExist(err) {
+			break
+		}
+		newPath = fmt.Sprintf("%s %d%s", base, i, COLLECTION_EXTENSION)
+	}
+	if err := ioutil.WriteFile(a.collectionPath(newPath), data, 0644); err != nil {
+		return a.OpenSaveResultView("Error duplicating request: "+err.Error(), g)
+	}
+	return a.showCollections(g, idx)
+}
+
+func (a *App) deleteCollectionEntry(g *gocui.Gui, v *gocui.View) error {
+	idx, e := a.selectedCollectionEntry(v)
+	if e == nil {
+		return nil
+	}
+	entry := *e
+	title := fmt.Sprintf("Delete %s? (enter to confirm, ctrl+q to cancel)", strings.TrimSuffix(entry.path, COLLECTION_EXTENSION))
+	if entry.isDir {
+		title = fmt.Sprintf("Delete folder %s and all of its requests? (enter to confirm, ctrl+q to cancel)", entry.path)
+	}
+	err := a.OpenDialog(title, "", g,
+		func(g *gocui.Gui, _ *gocui.View) error {
+			defer a.closePopup(g, SAVE_DIALOG_VIEW)
+			if err := os.RemoveAll(a.collectionPath(entry.path)); err != nil {
+				return a.OpenSaveResultView("Error deleting: "+err.Error(), g)
+			}
+			return a.showCollections(g, idx)
+		})
+	if dialog, viewErr := g.View(SAVE_DIALOG_VIEW); viewErr == nil {
+		dialog.Editable = false
+		g.Cursor = false
+	}
+	return err
+}
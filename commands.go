@@ -17,6 +17,9 @@ var COMMANDS map[string]func(string, *App) CommandFunc = map[string]func(string,
 	"submit": func(_ string, a *App) CommandFunc {
 		return a.SubmitRequest
 	},
+	"cancelRequest": func(_ string, a *App) CommandFunc {
+		return a.CancelRequest
+	},
 	"saveResponse": func(_ string, a *App) CommandFunc {
 		return func(g *gocui.Gui, _ *gocui.View) error {
 			return a.OpenSaveDialog(VIEW_TITLES[SAVE_RESPONSE_DIALOG_VIEW], g,
@@ -54,12 +57,86 @@ var COMMANDS map[string]func(string, *App) CommandFunc = map[string]func(string,
 				})
 		}
 	},
+	"loadHAR": func(_ string, a *App) CommandFunc {
+		return func(g *gocui.Gui, _ *gocui.View) error {
+			return a.OpenSaveDialog(VIEW_TITLES[LOAD_HAR_DIALOG_VIEW], g,
+				func(g *gocui.Gui, _ *gocui.View) error {
+					loadLocation := getViewValue(g, SAVE_DIALOG_VIEW)
+					a.closePopup(g, SAVE_DIALOG_VIEW)
+					if err := a.LoadHAR(loadLocation); err != nil {
+						return a.OpenSaveResultView("Error loading HAR: "+err.Error(), g)
+					}
+					a.SaveHistory(g)
+					return a.ToggleHistory(g, nil)
+				})
+		}
+	},
 	"saveRequest": func(_ string, a *App) CommandFunc {
 		return a.SaveRequest
 	},
 	"history": func(_ string, a *App) CommandFunc {
 		return a.ToggleHistory
 	},
+	"collections": func(_ string, a *App) CommandFunc {
+		return a.ToggleCollections
+	},
+	"importCurl": func(_ string, a *App) CommandFunc {
+		return a.OpenImportCurlDialog
+	},
+	"timing": func(_ string, a *App) CommandFunc {
+		return a.ToggleTiming
+	},
+	"tlsInfo": func(_ string, a *App) CommandFunc {
+		return a.ToggleTLSInfo
+	},
+	"grpcMethods": func(_ string, a *App) CommandFunc {
+		return a.ToggleGRPCMethods
+	},
+	"auth": func(_ string, a *App) CommandFunc {
+		return a.ToggleAuth
+	},
+	"redirects": func(_ string, a *App) CommandFunc {
+		return a.ToggleRedirects
+	},
+	"diff": func(_ string, a *App) CommandFunc {
+		return a.ToggleDiff
+	},
+	"assertions": func(_ string, a *App) CommandFunc {
+		return a.ToggleAssertions
+	},
+	"assertionResults": func(_ string, a *App) CommandFunc {
+		return a.ToggleAssertionResults
+	},
+	"captures": func(_ string, a *App) CommandFunc {
+		return a.ToggleCaptures
+	},
+	"sessionVariables": func(_ string, a *App) CommandFunc {
+		return a.ToggleSessionVariables
+	},
+	"cookies": func(_ string, a *App) CommandFunc {
+		return a.ToggleCookies
+	},
+	"toggleCookieJar": func(_ string, a *App) CommandFunc {
+		return a.ToggleCookieJar
+	},
+	"signing": func(_ string, a *App) CommandFunc {
+		return a.ToggleSigning
+	},
+	"sentRequest": func(_ string, a *App) CommandFunc {
+		return a.ToggleSentRequest
+	},
+	"toggleGraphQL": func(_ string, a *App) CommandFunc {
+		return a.ToggleGraphQL
+	},
+	"sendWebSocketMessage": func(_ string, a *App) CommandFunc {
+		return a.SendWebSocketMessage
+	},
+	"sendWebSocketPing": func(_ string, a *App) CommandFunc {
+		return a.SendWebSocketPing
+	},
+	"toggleWebSocketBinary": func(_ string, a *App) CommandFunc {
+		return a.ToggleWebSocketBinary
+	},
 	"quit": func(_ string, _ *App) CommandFunc {
 		return quit
 	},
@@ -108,6 +185,7 @@ var COMMANDS map[string]func(string, *App) CommandFunc = map[string]func(string,
 		return func(g *gocui.Gui, _ *gocui.View) error {
 			a.history = make([]*Request, 0, 31)
 			a.historyIndex = 0
+			a.SaveHistory(g)
 			a.Layout(g)
 			return nil
 		}
@@ -118,6 +196,11 @@ var COMMANDS map[string]func(string, *App) CommandFunc = map[string]func(string,
 			return nil
 		}
 	},
+	"switchEnvironment": func(args string, a *App) CommandFunc {
+		return func(g *gocui.Gui, _ *gocui.View) error {
+			return a.SwitchEnvironment(g, args)
+		}
+	},
 	"clearTabs": func(_ string, a *App) CommandFunc {
 		return func(g *gocui.Gui, _ *gocui.View) error {
 			if a.currentPopup != "" {
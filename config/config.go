@@ -30,22 +30,104 @@ func (d *Duration) UnmarshalText(text []byte) error {
 }
 
 type Config struct {
-	General GeneralOptions
-	Keys    map[string]map[string]string
+	General            GeneralOptions
+	Keys               map[string]map[string]string
+	Environments       map[string]map[string]string
+	ClientCertificates map[string]ClientCertificate
+	Auth               map[string]Auth
+	Signing            map[string]Signing
+}
+
+// Auth is a named authentication scheme which can be selected for
+// requests. The values can contain environment variables.
+type Auth struct {
+	// basic, bearer, digest or oauth2
+	Type     string
+	Username string
+	Password string
+	Token    string
+	// OAuth2 tokens are requested with the refresh token grant if a
+	// refresh token is set, otherwise with the client credentials grant
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RefreshToken string
+}
+
+// Signing is a named request signing scheme. The values can contain
+// environment variables.
+type Signing struct {
+	// aws-sigv4 or hmac
+	Type string
+	// AWS credentials are read from the environment or the shared
+	// credentials file. The service and region default to the ones of
+	// service.region.amazonaws.com hosts.
+	Service string
+	Region  string
+	Profile string
+	// HMAC signatures of the listed headers, "(request-target)" signs
+	// the method and the path
+	Key       string
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	// base64 or hex
+	Encoding string
+	// the signature is set in the header or the query parameter,
+	// formatted with the template
+	Header     string
+	QueryParam string
+	Format     string
+}
+
+// ClientCertificate is a PEM encoded certificate and private key used
+// for TLS client authentication
+type ClientCertificate struct {
+	Cert       string
+	Key        string
+	Passphrase string
 }
 
 type GeneralOptions struct {
+	Auth                   string
+	CACert                 string
+	CertExpiryWarningDays  int
+	CipherSuites           []string
+	ClientCert             string
+	ClientKey              string
+	ClientKeyPassphrase    string
+	CollectionsDir         string
+	ConnectTo              []string
+	Curves                 []string
 	ContextSpecificSearch  bool
+	CookieFile             string
+	CookieJar              bool
 	DefaultURLScheme       string
 	Editor                 string
+	Environment            string
 	FollowRedirects        bool
 	FormatJSON             bool
+	HistoryFile            string
+	HistoryLimit           int
+	HistorySizeLimit       int
+	HTTPVersion            string
+	MaxRedirects           int
 	Insecure               bool
+	PersistHistory         bool
+	PreserveRedirectMethod bool
 	PreserveScrollPosition bool
+	ProtoFiles             []string
+	ProtoImportPaths       []string
+	ReconnectEventStreams  bool
+	Resolve                []string
+	ServerName             string
+	Signing                string
 	StatusLine             string
 	TLSVersionMax          uint16
 	TLSVersionMin          uint16
 	Timeout                Duration
+	UnixSocket             string
 }
 
 var defaultTimeoutDuration, _ = time.ParseDuration("1m")
@@ -53,6 +135,7 @@ var defaultTimeoutDuration, _ = time.ParseDuration("1m")
 var DefaultKeys = map[string]map[string]string{
 	"global": {
 		"CtrlR": "submit",
+		"CtrlG": "cancelRequest",
 		"CtrlC": "quit",
 		"CtrlS": "saveResponse",
 		"CtrlF": "loadRequest",
@@ -67,6 +150,25 @@ var DefaultKeys = map[string]map[string]string{
 		"CtrlJ": "nextView",
 		"CtrlK": "prevView",
 		"AltH":  "history",
+		"AltE":  "switchEnvironment",
+		"AltC":  "collections",
+		"AltI":  "importCurl",
+		"AltA":  "loadHAR",
+		"AltT":  "timing",
+		"AltS":  "tlsInfo",
+		"AltG":  "grpcMethods",
+		"AltQ":  "toggleGraphQL",
+		"AltU":  "auth",
+		"AltN":  "signing",
+		"AltR":  "sentRequest",
+		"AltK":  "cookies",
+		"AltJ":  "toggleCookieJar",
+		"AltL":  "redirects",
+		"AltD":  "diff",
+		"AltX":  "assertions",
+		"AltP":  "assertionResults",
+		"AltY":  "captures",
+		"AltV":  "sessionVariables",
 		"F2":    "focus url",
 		"F3":    "focus get",
 		"F4":    "focus method",
@@ -92,23 +194,72 @@ var DefaultKeys = map[string]map[string]string{
 		"PageUp":    "pageUp",
 		"PageDown":  "pageDown",
 	},
+	"websocket-input": {
+		"Enter": "sendWebSocketMessage",
+		"CtrlB": "toggleWebSocketBinary",
+		"CtrlP": "sendWebSocketPing",
+	},
 	"help": {
 		"ArrowUp":   "scrollUp",
 		"ArrowDown": "scrollDown",
 		"PageUp":    "pageUp",
 		"PageDown":  "pageDown",
 	},
+	"tls-info": {
+		"ArrowUp":   "scrollUp",
+		"ArrowDown": "scrollDown",
+		"PageUp":    "pageUp",
+		"PageDown":  "pageDown",
+	},
+	"redirects": {
+		"ArrowUp":   "scrollUp",
+		"ArrowDown": "scrollDown",
+		"PageUp":    "pageUp",
+		"PageDown":  "pageDown",
+	},
+	"sent-request": {
+		"ArrowUp":   "scrollUp",
+		"ArrowDown": "scrollDown",
+		"PageUp":    "pageUp",
+		"PageDown":  "pageDown",
+	},
+	"response-diff": {
+		"ArrowUp":   "scrollUp",
+		"ArrowDown": "scrollDown",
+		"PageUp":    "pageUp",
+		"PageDown":  "pageDown",
+	},
+	"assertion-results": {
+		"ArrowUp":   "scrollUp",
+		"ArrowDown": "scrollDown",
+		"PageUp":    "pageUp",
+		"PageDown":  "pageDown",
+	},
+	"session-variables": {
+		"ArrowUp":   "scrollUp",
+		"ArrowDown": "scrollDown",
+		"PageUp":    "pageUp",
+		"PageDown":  "pageDown",
+	},
 }
 
 var DefaultConfig = Config{
 	General: GeneralOptions{
+		CertExpiryWarningDays:  30,
 		DefaultURLScheme:       "https",
 		Editor:                 "vim",
 		FollowRedirects:        true,
 		FormatJSON:             true,
+		HistoryLimit:           100,
+		HistorySizeLimit:       10 * 1024 * 1024,
+		HTTPVersion:            "2",
+		MaxRedirects:           10,
 		Insecure:               false,
+		PersistHistory:         true,
+		PreserveRedirectMethod: true,
 		PreserveScrollPosition: true,
-		StatusLine:             "[wuzz {{.Version}}]{{if .Duration}} [Response time: {{.Duration}}]{{end}} [Request no.: {{.RequestNumber}}/{{.HistorySize}}] [Search type: {{.SearchType}}]{{if .DisableRedirect}} [Redirects Restricted Mode {{.DisableRedirect}}]{{end}}",
+		ReconnectEventStreams:  true,
+		StatusLine:             "[wuzz {{.Version}}]{{if .Duration}} [Response time: {{.Duration}}]{{end}}{{if .BytesReceived}} [Received: {{.BytesReceived}}]{{end}} [Request no.: {{.RequestNumber}}/{{.HistorySize}}]{{if .Redirects}} [Redirects: {{.Redirects}}]{{end}}{{if .Assertions}} [Assertions: {{.Assertions}}]{{end}} [Search type: {{.SearchType}}]{{if .DisableRedirect}} [Redirects Restricted Mode {{.DisableRedirect}}]{{end}}{{if .Environment}} [Environment: {{.Environment}}]{{end}}{{if .Variables}} [Variables: {{.Variables}}]{{end}}{{if .Auth}} [Auth: {{.Auth}}]{{end}}{{if .Signing}} [Signing: {{.Signing}}]{{end}}{{if .Cookies}} [Cookies: {{.Cookies}}]{{end}}{{if .CertificateWarning}} [{{.CertificateWarning}}]{{end}}{{if .HistoryError}} [History error: {{.HistoryError}}]{{end}}",
 		Timeout: Duration{
 			defaultTimeoutDuration,
 		},
@@ -158,11 +309,13 @@ func GetDefaultConfigLocation() string {
 	var configFolderLocation string
 	switch runtime.GOOS {
 	case "linux":
-		// Use the XDG_CONFIG_HOME variable if it is set, otherwise
-		// $HOME/.config/wuzz/config.toml
+		// Use $XDG_CONFIG_HOME/wuzz/config.toml if the XDG_CONFIG_HOME
+		// variable is set, otherwise $HOME/.config/wuzz/config.toml. The
+		// history, the collections and the cookies are stored next to
+		// the config file.
 		xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
 		if xdgConfigHome != "" {
-			configFolderLocation = xdgConfigHome
+			configFolderLocation = filepath.Join(xdgConfigHome, "wuzz")
 		} else {
 			configFolderLocation, _ = homedir.Expand("~/.config/wuzz/")
 		}
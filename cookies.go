@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/net/publicsuffix"
+)
+
+const COOKIE_FILE_NAME = "cookies.txt"
+
+// prefix of HttpOnly cookies in cookie files
+const HTTP_ONLY_PREFIX = "#HttpOnly_"
+
+// cookieSession is the cookie jar of the requests. The cookies are
+// matched by a cookiejar.Jar, the accepted cookies are recorded to be
+// listed and persisted in the cookie file format of curl.
+type cookieSession struct {
+	sync.Mutex
+	jar *cookiejar.Jar
+	// by domain, path and name
+	cookies map[string]*sessionCookie
+}
+
+type sessionCookie struct {
+	// without a leading dot
+	Domain   string
+	HostOnly bool
+	Path     string
+	Name     string
+	Value    string
+	Secure   bool
+	HttpOnly bool
+	// zero for session cookies
+	Expires time.Time
+}
+
+func newCookieSession() *cookieSession {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &cookieSession{jar: jar, cookies: make(map[string]*sessionCookie)}
+}
+
+func (c *sessionCookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+// url returns a URL the cookie is sent to
+func (c *sessionCookie) url() *url.URL {
+	return &url.URL{Scheme: "https", Host: c.Domain, Path: c.Path}
+}
+
+func (c *sessionCookie) httpCookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		Expires:  c.Expires,
+	}
+	if !c.HostOnly {
+		cookie.Domain = c.Domain
+	}
+	return cookie
+}
+
+func (c *sessionCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// defaultCookiePath returns the directory of the request path (RFC 6265
+// section 5.1.4)
+func defaultCookiePath(u *url.URL) string {
+	if i := strings.LastIndex(u.Path, "/"); i > 0 {
+		return u.Path[:i]
+	}
+	return "/"
+}
+
+func (s *cookieSession) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+func (s *cookieSession) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.Lock()
+	defer s.Unlock()
+	s.jar.SetCookies(u, cookies)
+	now := time.Now()
+	for _, cookie := range cookies {
+		c := &sessionCookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(cookie.Domain, ".")),
+			Path:     cookie.Path,
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			Expires:  cookie.Expires,
+		}
+		if c.Domain == "" {
+			c.Domain = strings.ToLower(u.Hostname())
+			c.HostOnly = true
+		}
+		if !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultCookiePath(u)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			c.Expires = now
+		case cookie.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		if c.expired(now) {
+			delete(s.cookies, c.key())
+			continue
+		}
+		// cookies rejected by the jar are not recorded
+		for _, accepted := range s.jar.Cookies(c.url()) {
+			if accepted.Name == c.Name && accepted.Value == c.Value {
+				s.cookies[c.key()] = c
+				break
+			}
+		}
+	}
+}
+
+// set stores a cookie as if it was received from its domain
+func (s *cookieSession) set(c *sessionCookie) {
+	s.SetCookies(c.url(), []*http.Cookie{c.httpCookie()})
+}
+
+func (s *cookieSession) remove(c *sessionCookie) {
+	cookie := c.httpCookie()
+	cookie.MaxAge = -1
+	s.SetCookies(c.url(), []*http.Cookie{cookie})
+}
+
+// list returns the unexpired cookies sorted by domain, path and name
+func (s *cookieSession) list() []*sessionCookie {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	cookies := make([]*sessionCookie, 0, len(s.cookies))
+	for key, c := range s.cookies {
+		if c.expired(now) {
+			delete(s.cookies, key)
+			continue
+		}
+		cookies = append(cookies, c)
+	}
+	sort.Slice(cookies, func(i, j int) bool {
+		if cookies[i].Domain != cookies[j].Domain {
+			return cookies[i].Domain < cookies[j].Domain
+		}
+		if cookies[i].Path != cookies[j].Path {
+			return cookies[i].Path < cookies[j].Path
+		}
+		return cookies[i].Name < cookies[j].Name
+	})
+	return cookies
+}
+
+// readCookieFile parses a Netscape cookie file as written by curl and
+// browser extensions. A missing file holds no cookies.
+func readCookieFile(location string) ([]*sessionCookie, error) {
+	f, err := os.Open(location)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cookies := make([]*sessionCookie, 0, 16)
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, HTTP_ONLY_PREFIX)
+		line = strings.TrimPrefix(line, HTTP_ONLY_PREFIX)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// cookies without a value
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%v:%d: invalid cookie line", location, lineNumber)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%d: invalid expiry: %v", location, lineNumber, fields[4])
+		}
+		c := &sessionCookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+func writeCookieFile(location string, cookies []*sessionCookie) error {
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n# This file was generated by wuzz\n\n")
+	for _, c := range cookies {
+		domain, subdomains := c.Domain, "FALSE"
+		if !c.HostOnly {
+			domain, subdomains = "."+c.Domain, "TRUE"
+		}
+		if c.HttpOnly {
+			domain = HTTP_ONLY_PREFIX + domain
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		secure := "FALSE"
+		if c.Secure {
+			secure = "TRUE"
+		}
+		fmt.Fprintf(&b, "%v\t%v\t%v\t%v\t%d\t%v\t%v\n", domain, subdomains, c.Path, secure, expires, c.Name, c.Value)
+	}
+	if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
+		return err
+	}
+	tmpLocation := location + ".tmp"
+	if err := os.WriteFile(tmpLocation, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpLocation, location)
+}
+
+func (a *App) cookieFileLocation() string {
+	if a.config.General.CookieFile != "" {
+		location, err := homedir.Expand(a.config.General.CookieFile)
+		if err == nil {
+			return location
+		}
+		return a.config.General.CookieFile
+	}
+	return filepath.Join(a.configDir, COOKIE_FILE_NAME)
+}
+
+// initCookieJar starts the cookie session of the requests, the cookies of
+// the cookie file and of the files given with --cookie are loaded
+func (a *App) initCookieJar() error {
+	if !a.config.General.CookieJar {
+		a.cookies = nil
+		CLIENT.Jar = nil
+		WEBSOCKET_CLIENT.Jar = nil
+		return nil
+	}
+	if a.cookies == nil {
+		a.cookies = newCookieSession()
+		a.cookieImports = append([]string{a.cookieFileLocation()}, a.cookieImports...)
+	}
+	for _, location := range a.cookieImports {
+		location, err := homedir.Expand(location)
+		if err != nil {
+			return err
+		}
+		cookies, err := readCookieFile(location)
+		if err != nil {
+			return err
+		}
+		for _, c := range cookies {
+			a.cookies.set(c)
+		}
+	}
+	a.cookieImports = nil
+	CLIENT.Jar = a.cookies
+	WEBSOCKET_CLIENT.Jar = a.cookies
+	return nil
+}
+
+// SaveCookies writes the cookies of the session to the cookie file
+func (a *App) SaveCookies() error {
+	if a.cookies == nil {
+		return nil
+	}
+	return writeCookieFile(a.cookieFileLocation(), a.cookies.list())
+}
+
+// ToggleCookieJar starts or ends the cookie session, the cookies are kept
+// in the cookie file
+func (a *App) ToggleCookieJar(g *gocui.Gui, _ *gocui.View) error {
+	if err := a.SaveCookies(); err != nil {
+		return a.OpenSaveResultView("Error saving cookies: "+err.Error(), g)
+	}
+	a.config.General.CookieJar = !a.config.General.CookieJar
+	if err := a.initCookieJar(); err != nil {
+		a.config.General.CookieJar = false
+		a.initCookieJar()
+		return a.OpenSaveResultView("Error loading cookies: "+err.Error(), g)
+	}
+	refreshStatusLine(a, g)
+	return nil
+}
+
+// ToggleCookies lists the cookies of the session by domain
+func (a *App) ToggleCookies(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == COOKIES_VIEW {
+		a.closePopup(g, COOKIES_VIEW)
+		return nil
+	}
+	if a.cookies == nil {
+		return a.OpenSaveResultView("The cookie jar is disabled", g)
+	}
+	return a.showCookies(g, 0)
+}
+
+func (a *App) showCookies(g *gocui.Gui, cursor int) error {
+	cookies := a.cookies.list()
+	// domain lines have no cookie
+	a.cookieList = make([]*sessionCookie, 0, len(cookies)*2)
+	maxX, maxY := g.Size()
+	list, err := a.CreatePopupView(COOKIES_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	list.Title = VIEW_TITLES[COOKIES_VIEW]
+	if len(cookies) == 0 {
+		fmt.Fprint(list, "[!] No cookies")
+	}
+	for i, c := range cookies {
+		if i == 0 || c.Domain != cookies[i-1].Domain {
+			fmt.Fprintf(list, "\x1b[0;32m%v\x1b[0;0m\n", c.Domain)
+			a.cookieList = append(a.cookieList, nil)
+		}
+		attributes := []string{"path=" + c.Path}
+		if c.Expires.IsZero() {
+			attributes = append(attributes, "session")
+		} else {
+			attributes = append(attributes, "expires="+c.Expires.Format(time.RFC1123))
+		}
+		if !c.HostOnly {
+			attributes = append(attributes, "subdomains")
+		}
+		if c.Secure {
+			attributes = append(attributes, "secure")
+		}
+		if c.HttpOnly {
+			attributes = append(attributes, "httponly")
+		}
+		fmt.Fprintf(list, "  \x1b[0;33m%v\x1b[0;0m=%v  %v\n", c.Name, c.Value, strings.Join(attributes, " "))
+		a.cookieList = append(a.cookieList, c)
+	}
+	if cursor >= len(a.cookieList) {
+		cursor = len(a.cookieList) - 1
+	}
+	if cursor > 0 {
+		list.SetCursor(0, cursor)
+	}
+	g.SetViewOnTop(COOKIES_VIEW)
+	g.SetCurrentView(COOKIES_VIEW)
+	return nil
+}
+
+func (a *App) selectedCookie(v *gocui.View) (int, *sessionCookie) {
+	_, cy := v.Cursor()
+	_, oy := v.Origin()
+	idx := cy + oy
+	if idx >= len(a.cookieList) {
+		return idx, nil
+	}
+	return idx, a.cookieList[idx]
+}
+
+func (a *App) editCookie(g *gocui.Gui, v *gocui.View) error {
+	idx, c := a.selectedCookie(v)
+	if c == nil {
+		return nil
+	}
+	title := fmt.Sprintf("Value of %v (enter to submit, ctrl+q to cancel)", c.Name)
+	return a.OpenDialog(title, c.Value, g,
+		func(g *gocui.Gui, _ *gocui.View) error {
+			defer a.closePopup(g, SAVE_DIALOG_VIEW)
+			edited := *c
+			edited.Value = getViewValue(g, SAVE_DIALOG_VIEW)
+			a.cookies.set(&edited)
+			if err := a.SaveCookies(); err != nil {
+				return a.OpenSaveResultView("Error saving cookies: "+err.Error(), g)
+			}
+			return a.showCookies(g, idx)
+		})
+}
+
+func (a *App) deleteCookie(g *gocui.Gui, v *gocui.View) error {
+	idx, c := a.selectedCookie(v)
+	if c == nil {
+		return nil
+	}
+	a.cookies.remove(c)
+	if err := a.SaveCookies(); err != nil {
+		return a.OpenSaveResultView("Error saving cookies: "+err.Error(), g)
+	}
+	return a.showCookies(g, idx)
+}
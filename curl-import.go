@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// splitShellWords tokenizes a command line following the POSIX shell
+// quoting rules, including line continuations and $'...' strings
+func splitShellWords(line string) ([]string, error) {
+	words := make([]string, 0, 16)
+	word := &strings.Builder{}
+	inWord := false
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(runes) {
+				return nil, errors.New("Unterminated escape sequence")
+			}
+			i++
+			// line continuations, also of commands copied from Windows
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+			}
+			if runes[i] == '\n' {
+				continue
+			}
+			word.WriteRune(runes[i])
+			inWord = true
+		case c == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != '\'' {
+				j++
+			}
+			if j >= len(runes) {
+				return nil, errors.New("Unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : j]))
+			i = j
+			inWord = true
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			n, err := readANSICQuoted(runes[i+2:], word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("Unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// readANSICQuoted decodes the content of a $'...' string and returns the
+// number of consumed runes including the closing quote
+func readANSICQuoted(runes []rune, word *strings.Builder) (int, error) {
+	escapes := map[rune]string{
+		'n': "\n", 't': "\t", 'r': "\r", 'a': "\a", 'b': "\b", 'e': "\x1b",
+		'f': "\f", 'v': "\v", '\\': "\\", '\'': "'", '"': "\"", '?': "?",
+	}
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\'':
+			return i + 1, nil
+		case '\\':
+			if i+1 >= len(runes) {
+				return 0, errors.New("Unterminated escape sequence")
+			}
+			i++
+			if e, found := escapes[runes[i]]; found {
+				word.WriteString(e)
+				continue
+			}
+			if runes[i] == 'x' || runes[i] == 'u' {
+				maxLen := 2
+				if runes[i] == 'u' {
+					maxLen = 4
+				}
+				j := i + 1
+				for j < len(runes) && j-i-1 < maxLen && strings.ContainsRune("0123456789abcdefABCDEF", runes[j]) {
+					j++
+				}
+				code, err := strconv.ParseUint(string(runes[i+1:j]), 16, 32)
+				if err != nil {
+					return 0, errors.New("Invalid escape sequence")
+				}
+				if runes[i] == 'x' {
+					word.WriteByte(byte(code))
+				} else {
+					word.WriteRune(rune(code))
+				}
+				i = j - 1
+				continue
+			}
+			word.WriteRune('\\')
+			word.WriteRune(runes[i])
+		default:
+			word.WriteRune(runes[i])
+		}
+	}
+	return 0, errors.New("Unterminated $' quote")
+}
+
+func (a *App) OpenImportCurlDialog(g *gocui.Gui, _ *gocui.View) error {
+	dialog, err := a.CreatePopupView(IMPORT_CURL_DIALOG_VIEW, 80, 10, g)
+	if err != nil {
+		return err
+	}
+	g.Cursor = true
+
+	dialog.Title = VIEW_TITLES[IMPORT_CURL_DIALOG_VIEW]
+	dialog.Editable = true
+	dialog.Wrap = true
+	dialog.Highlight = false
+
+	g.SetViewOnTop(IMPORT_CURL_DIALOG_VIEW)
+	g.SetCurrentView(IMPORT_CURL_DIALOG_VIEW)
+	return nil
+}
+
+// ImportCurl fills the request views from a curl command line
+func (a *App) ImportCurl(g *gocui.Gui, command string) error {
+	args, err := splitShellWords(strings.TrimSpace(command))
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return errors.New("Not a curl command")
+	}
+	a.restoreRequest(g, 0, true)
+	if err := a.ParseArgs(g, args); err != nil {
+		return err
+	}
+	return a.applyConfig(g)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+		err   string
+	}{
+		{"", []string{}, ""},
+		{"curl  -X\tPOST\n", []string{"curl", "-X", "POST"}, ""},
+		{`curl 'https://example.com/?a=1&b=2'`, []string{"curl", "https://example.com/?a=1&b=2"}, ""},
+		{`-d 'a "b" \n'`, []string{"-d", `a "b" \n`}, ""},
+		{`-H "X-A: \"b\" \$c \d"`, []string{"-H", `X-A: "b" $c \d`}, ""},
+		{`a\ b \'c\'`, []string{"a b", "'c'"}, ""},
+		{`x'y'"z"`, []string{"xyz"}, ""},
+		{`'' ""`, []string{"", ""}, ""},
+		{"curl \\\n  -X POST \\\r\n", []string{"curl", "-X", "POST"}, ""},
+		{"a\\\rb", []string{"a\rb"}, ""},
+		{"\"a\\\nb\"", []string{"ab"}, ""},
+		{`$'a\nb\t\'c\' \x41é \q'`, []string{"a\nb\t'c' Aé \\q"}, ""},
+		{`$'\xzz'`, nil, "Invalid escape sequence"},
+		{`$'abc`, nil, "Unterminated $' quote"},
+		{`$'abc\`, nil, "Unterminated escape sequence"},
+		{`'abc`, nil, "Unterminated single quote"},
+		{`"abc`, nil, "Unterminated double quote"},
+		{`abc\`, nil, "Unterminated escape sequence"},
+	}
+	for _, test := range tests {
+		words, err := splitShellWords(test.line)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Expected error %q for %q, got %v", test.err, test.line, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, test.words) {
+			t.Errorf("Expected %q for %q, got %q", test.words, test.line, words)
+		}
+	}
+}
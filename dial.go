@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// dialOverrides changes the addresses dialed by the transport: every
+// connection is made to a unix socket, or the host and port are replaced
+// like with curl's --connect-to and --resolve options. The URL still
+// supplies the Host header and the TLS server name.
+type dialOverrides struct {
+	unixSocket string
+	connectTo  []connectTo
+	// addresses by lowercase "host:port"
+	resolve map[string][]string
+	dial    func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// connectTo replaces host:port with toHost:toPort, empty fields match any
+// value or keep the original value
+type connectTo struct {
+	host, port, toHost, toPort string
+}
+
+// splitAddressList splits colon separated fields, colons of bracketed IPv6
+// addresses are kept. The last of n fields holds the remainder.
+func splitAddressList(s string, n int) []string {
+	fields := make([]string, 0, n)
+	start, brackets := 0, false
+	for i, c := range s {
+		switch {
+		case c == '[':
+			brackets = true
+		case c == ']':
+			brackets = false
+		case c == ':' && !brackets && len(fields) < n-1:
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, s[start:])
+}
+
+func trimBrackets(host string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// parseResolve parses a HOST:PORT:ADDRESS[,ADDRESS]... value
+func parseResolve(value string) (string, []string, error) {
+	fields := splitAddressList(value, 3)
+	if len(fields) != 3 || fields[0] == "" || fields[1] == "" || fields[2] == "" {
+		return "", nil, fmt.Errorf("Invalid resolve value: %v (HOST:PORT:ADDRESS)", value)
+	}
+	addrs := strings.Split(fields[2], ",")
+	for i, addr := range addrs {
+		addrs[i] = trimBrackets(addr)
+	}
+	return strings.ToLower(net.JoinHostPort(trimBrackets(fields[0]), fields[1])), addrs, nil
+}
+
+// parseConnectTo parses a HOST1:PORT1:HOST2:PORT2 value
+func parseConnectTo(value string) (connectTo, error) {
+	fields := splitAddressList(value, 4)
+	if len(fields) != 4 {
+		return connectTo{}, fmt.Errorf("Invalid connect-to value: %v (HOST1:PORT1:HOST2:PORT2)", value)
+	}
+	return connectTo{
+		host:   strings.ToLower(trimBrackets(fields[0])),
+		port:   fields[1],
+		toHost: trimBrackets(fields[2]),
+		toPort: fields[3],
+	}, nil
+}
+
+// newDialOverrides returns nil if no override is configured
+func newDialOverrides(unixSocket string, connectToValues, resolveValues []string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (*dialOverrides, error) {
+	if unixSocket == "" && len(connectToValues) == 0 && len(resolveValues) == 0 {
+		return nil, nil
+	}
+	d := &dialOverrides{
+		unixSocket: unixSocket,
+		resolve:    make(map[string][]string, len(resolveValues)),
+		dial:       dial,
+	}
+	for _, value := range connectToValues {
+		c, err := parseConnectTo(value)
+		if err != nil {
+			return nil, err
+		}
+		d.connectTo = append(d.connectTo, c)
+	}
+	for _, value := range resolveValues {
+		hostPort, addrs, err := parseResolve(value)
+		if err != nil {
+			return nil, err
+		}
+		d.resolve[hostPort] = addrs
+	}
+	return d, nil
+}
+
+func (d *dialOverrides) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.unixSocket != "" {
+		return (&net.Dialer{}).DialContext(ctx, "unix", d.unixSocket)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range d.connectTo {
+		if (c.host != "" && c.host != strings.ToLower(host)) || (c.port != "" && c.port != port) {
+			continue
+		}
+		if c.toHost != "" {
+			host = c.toHost
+		}
+		if c.toPort != "" {
+			port = c.toPort
+		}
+		break
+	}
+	addrs, found := d.resolve[strings.ToLower(net.JoinHostPort(host, port))]
+	if !found {
+		return d.dial(ctx, network, net.JoinHostPort(host, port))
+	}
+	// the addresses are tried in order
+	for _, a := range addrs {
+		var conn net.Conn
+		conn, err = d.dial(ctx, network, net.JoinHostPort(a, port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// initDialOverrides sets the dialer of TRANSPORT. Connections are made
+// through the SOCKS proxy if one is set.
+func (a *App) initDialOverrides() error {
+	dial := (&net.Dialer{}).DialContext
+	if TRANSPORT.Dial != nil {
+		socksDial := TRANSPORT.Dial
+		dial = func(_ context.Context, network, addr string) (net.Conn, error) {
+			return socksDial(network, addr)
+		}
+	}
+	d, err := newDialOverrides(a.config.General.UnixSocket, a.config.General.ConnectTo, a.config.General.Resolve, dial)
+	if err != nil || d == nil {
+		return err
+	}
+	TRANSPORT.DialContext = d.DialContext
+	if d.unixSocket != "" {
+		// the socket is the server
+		TRANSPORT.Proxy = nil
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		value    string
+		hostPort string
+		addrs    []string
+		valid    bool
+	}{
+		{"Example.com:443:127.0.0.1", "example.com:443", []string{"127.0.0.1"}, true},
+		{"example.com:80:127.0.0.1,[::1]", "example.com:80", []string{"127.0.0.1", "::1"}, true},
+		{"example.com:80:::1", "example.com:80", []string{"::1"}, true},
+		{"[::1]:8080:[fe80::1]", "[::1]:8080", []string{"fe80::1"}, true},
+		{"example.com:443", "", nil, false},
+		{"example.com", "", nil, false},
+		{":443:127.0.0.1", "", nil, false},
+		{"example.com::127.0.0.1", "", nil, false},
+		{"example.com:443:", "", nil, false},
+		{"[::1:443:127.0.0.1", "", nil, false},
+	}
+	for _, test := range tests {
+		hostPort, addrs, err := parseResolve(test.value)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected %q to be invalid, got %v %v", test.value, hostPort, addrs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.value, err)
+			continue
+		}
+		if hostPort != test.hostPort || !reflect.DeepEqual(addrs, test.addrs) {
+			t.Errorf("Unexpected result for %q: %v %v", test.value, hostPort, addrs)
+		}
+	}
+}
+
+func TestParseConnectTo(t *testing.T) {
+	tests := []struct {
+		value string
+		c     connectTo
+		valid bool
+	}{
+		{"Example.com:443:backend:8443", connectTo{"example.com", "443", "backend", "8443"}, true},
+		{"::backend:", connectTo{"", "", "backend", ""}, true},
+		{"[::1]:443:[fe80::1]:8443", connectTo{"::1", "443", "fe80::1", "8443"}, true},
+		{"example.com:443:[::1]:", connectTo{"example.com", "443", "::1", ""}, true},
+		{"example.com:443:backend", connectTo{}, false},
+		{"example.com", connectTo{}, false},
+		{"[::1]:443:backend", connectTo{}, false},
+	}
+	for _, test := range tests {
+		c, err := parseConnectTo(test.value)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected %q to be invalid, got %+v", test.value, c)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.value, err)
+			continue
+		}
+		if c != test.c {
+			t.Errorf("Unexpected result for %q: %+v", test.value, c)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mattn/go-runewidth"
+)
+
+// DIFF_MAX_EDITS limits the work of the line diff, larger differences are
+// shown as a replacement of the remaining lines
+const DIFF_MAX_EDITS = 1000
+
+const (
+	DIFF_EQUAL = iota
+	DIFF_DELETE
+	DIFF_INSERT
+)
+
+type diffLine struct {
+	op   int
+	text string
+}
+
+// diffLines returns the line based difference of a and b
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		lines = append(lines, diffLine{DIFF_EQUAL, l})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{DIFF_EQUAL, l})
+	}
+	return lines
+}
+
+// myersDiff finds the shortest edit script of a and b
+func myersDiff(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	v := make([]int, 2*max+1)
+	// trace[d] holds the furthest points of the diagonals -d..d before step d
+	trace := make([][]int, 0)
+	for d := 0; d <= max; d++ {
+		if d > DIFF_MAX_EDITS {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[max-d:max+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(trace, a, b)
+			}
+		}
+	}
+	return replaceLines(a, b)
+}
+
+func backtrackDiff(trace [][]int, a, b []string) []diffLine {
+	x, y := len(a), len(b)
+	lines := make([]diffLine, 0, x+y)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			lines = append(lines, diffLine{DIFF_EQUAL, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			lines = append(lines, diffLine{DIFF_INSERT, b[y-1]})
+		} else {
+			lines = append(lines, diffLine{DIFF_DELETE, a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		lines = append(lines, diffLine{DIFF_EQUAL, a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+func replaceLines(a, b []string) []diffLine {
+	lines := make([]diffLine, 0, len(a)+len(b))
+	for _, l := range a {
+		lines = append(lines, diffLine{DIFF_DELETE, l})
+	}
+	for _, l := range b {
+		lines = append(lines, diffLine{DIFF_INSERT, l})
+	}
+	return lines
+}
+
+// normalizeJSON sorts the keys of a JSON document and indents it, so only
+// changed values show up in a diff
+func normalizeJSON(data []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil, false
+	}
+	normalized, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, false
+	}
+	return normalized, true
+}
+
+func diffHeaderLines(r *Request) []string {
+	lines := []string{fmt.Sprintf("%v %v %v", r.Proto, r.StatusCode, http.StatusText(r.StatusCode))}
+	names := make([]string, 0, len(r.RawResponseHeaders))
+	for name := range r.RawResponseHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%v: %v", name, strings.Join(r.RawResponseHeaders[name], ",")))
+	}
+	return lines
+}
+
+func diffBodyLines(r *Request) []string {
+	body := r.RawResponseBody
+	if normalized, ok := normalizeJSON(body); ok {
+		body = normalized
+	} else if !utf8.Valid(body) {
+		body = []byte(hex.Dump(body))
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// writeSideBySide prints the difference of two line lists in two columns,
+// removed lines on the left and added lines on the right
+func writeSideBySide(v *gocui.View, lines []diffLine, width int) {
+	column := func(text string, color int) string {
+		text = strings.ReplaceAll(text, "\t", "    ")
+		text = runewidth.FillRight(runewidth.Truncate(text, width, "…"), width)
+		if color == 0 {
+			return text
+		}
+		return fmt.Sprintf("\x1b[0;%dm%v\x1b[0;0m", color, text)
+	}
+	separator := func(changed bool) string {
+		if changed {
+			return " \x1b[0;33m|\x1b[0;0m "
+		}
+		return " | "
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == DIFF_EQUAL {
+			fmt.Fprintln(v, column(lines[i].text, 0)+separator(false)+column(lines[i].text, 0))
+			i++
+			continue
+		}
+		var deleted, inserted []string
+		for ; i < len(lines) && lines[i].op != DIFF_EQUAL; i++ {
+			if lines[i].op == DIFF_DELETE {
+				deleted = append(deleted, lines[i].text)
+			} else {
+				inserted = append(inserted, lines[i].text)
+			}
+		}
+		for j := 0; j < len(deleted) || j < len(inserted); j++ {
+			left, right := column("", 0), column("", 0)
+			if j < len(deleted) {
+				left = column(deleted[j], 31)
+			}
+			if j < len(inserted) {
+				right = column(inserted[j], 32)
+			}
+			fmt.Fprintln(v, left+separator(true)+right)
+		}
+	}
+}
+
+func diffRequestTitle(i int, r *Request) string {
+	return fmt.Sprintf("[%02d] %v %v", i, r.Method, r.Url)
+}
+
+// ToggleDiff lists the history to pick two responses to compare
+func (a *App) ToggleDiff(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == DIFF_VIEW || a.currentPopup == RESPONSE_DIFF_VIEW {
+		a.closePopup(g, a.currentPopup)
+		return nil
+	}
+	if len(a.history) < 2 {
+		return a.OpenSaveResultView("At least two requests are needed in the history to compare responses", g)
+	}
+	a.diffBase = -1
+	list, err := a.CreatePopupView(DIFF_VIEW, 100, len(a.history), g)
+	if err != nil {
+		return err
+	}
+	list.Title = VIEW_TITLES[DIFF_VIEW]
+	a.writeDiffList(list)
+	list.SetCursor(0, a.historyIndex)
+	g.SetViewOnTop(DIFF_VIEW)
+	g.SetCurrentView(DIFF_VIEW)
+	return nil
+}
+
+func (a *App) writeDiffList(v *gocui.View) {
+	v.Clear()
+	for i, r := range a.history {
+		marker := "  "
+		if i == a.diffBase {
+			marker = "* "
+		}
+		fmt.Fprintf(v, "%v%v (%v)\n", marker, diffRequestTitle(i, r), r.StatusCode)
+	}
+}
+
+// selectDiffEntry marks the first response, the second one opens the diff
+func (a *App) selectDiffEntry(g *gocui.Gui, v *gocui.View) error {
+	_, cy := v.Cursor()
+	idx := viewCursorLine(g, DIFF_VIEW)
+	if idx >= len(a.history) {
+		return nil
+	}
+	if a.diffBase < 0 || a.diffBase == idx {
+		if a.diffBase == idx {
+			a.diffBase = -1
+		} else {
+			a.diffBase = idx
+		}
+		a.writeDiffList(v)
+		v.SetCursor(0, cy)
+		return nil
+	}
+	return a.showResponseDiff(g, a.diffBase, idx)
+}
+
+func (a *App) showResponseDiff(g *gocui.Gui, first, second int) error {
+	maxX, maxY := g.Size()
+	v, err := a.CreatePopupView(RESPONSE_DIFF_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	v.Title = VIEW_TITLES[RESPONSE_DIFF_VIEW]
+	v.Highlight = false
+	g.SetViewOnTop(RESPONSE_DIFF_VIEW)
+	g.SetCurrentView(RESPONSE_DIFF_VIEW)
+
+	r1, r2 := a.history[first], a.history[second]
+	width, _ := v.Size()
+	column := (width - 3) / 2
+	if column < 1 {
+		column = 1
+	}
+	fmt.Fprintf(v, "\x1b[0;32m%v\x1b[0;0m | \x1b[0;32m%v\x1b[0;0m\n",
+		runewidth.FillRight(runewidth.Truncate(diffRequestTitle(first, r1), column, "…"), column),
+		runewidth.Truncate(diffRequestTitle(second, r2), column, "…"))
+
+	fmt.Fprintln(v, "\x1b[0;33m[Headers]\x1b[0;0m")
+	writeSideBySide(v, diffLines(diffHeaderLines(r1), diffHeaderLines(r2)), column)
+	fmt.Fprintln(v, "\x1b[0;33m[Body]\x1b[0;0m")
+	body1, body2 := diffBodyLines(r1), diffBodyLines(r2)
+	if len(body1) == 0 && len(body2) == 0 {
+		fmt.Fprintln(v, "[!] Empty response bodies")
+		return nil
+	}
+	bodyDiff := diffLines(body1, body2)
+	writeSideBySide(v, bodyDiff, column)
+	for _, l := range bodyDiff {
+		if l.op != DIFF_EQUAL {
+			return nil
+		}
+	}
+	fmt.Fprintln(v, "\x1b[0;32m[!] Response bodies are identical\x1b[0;0m")
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMyersDiff(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		lines []diffLine
+	}{
+		{"", "", nil},
+		{"a b c", "a b c", []diffLine{{DIFF_EQUAL, "a"}, {DIFF_EQUAL, "b"}, {DIFF_EQUAL, "c"}}},
+		{"", "a b", []diffLine{{DIFF_INSERT, "a"}, {DIFF_INSERT, "b"}}},
+		{"a b", "", []diffLine{{DIFF_DELETE, "a"}, {DIFF_DELETE, "b"}}},
+		{"a c", "a b c d", []diffLine{{DIFF_EQUAL, "a"}, {DIFF_INSERT, "b"}, {DIFF_EQUAL, "c"}, {DIFF_INSERT, "d"}}},
+		{"a b c d", "b d", []diffLine{{DIFF_DELETE, "a"}, {DIFF_EQUAL, "b"}, {DIFF_DELETE, "c"}, {DIFF_EQUAL, "d"}}},
+		{"a b c", "a x c", []diffLine{{DIFF_EQUAL, "a"}, {DIFF_DELETE, "b"}, {DIFF_INSERT, "x"}, {DIFF_EQUAL, "c"}}},
+	}
+	for _, test := range tests {
+		lines := myersDiff(strings.Fields(test.a), strings.Fields(test.b))
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("Unexpected diff of %q and %q: %v", test.a, test.b, lines)
+		}
+	}
+}
+
+func TestMyersDiffShortest(t *testing.T) {
+	// the example of "An O(ND) Difference Algorithm and Its Variations"
+	a, b := strings.Split("abcabba", ""), strings.Split("cbabac", "")
+	lines := myersDiff(a, b)
+	var oldLines, newLines []string
+	edits := 0
+	for _, l := range lines {
+		if l.op != DIFF_INSERT {
+			oldLines = append(oldLines, l.text)
+		}
+		if l.op != DIFF_DELETE {
+			newLines = append(newLines, l.text)
+		}
+		if l.op != DIFF_EQUAL {
+			edits++
+		}
+	}
+	if !reflect.DeepEqual(oldLines, a) || !reflect.DeepEqual(newLines, b) {
+		t.Errorf("Expected the diff to contain both inputs, got %v", lines)
+	}
+	if edits != 5 {
+		t.Errorf("Expected 5 edits, got %d", edits)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// variablePattern matches {{name}} placeholders in request views
+var variablePattern = regexp.MustCompile(`{{\s*([a-zA-Z0-9_.-]+)\s*}}`)
+
+// substituteVariables replaces {{name}} placeholders with the captured
+// session variables or the values of the active environment. Unknown
+// variables are left untouched.
+func (a *App) substituteVariables(s string) string {
+	return a.variableSubstitution()(s)
+}
+
+// variableSubstitution returns substituteVariables with the environment
+// active at the time of the call. Requests sent in the background use it
+// instead of reading the environment switched by the UI goroutine.
+func (a *App) variableSubstitution() func(string) string {
+	vars := a.config.Environments[a.config.General.Environment]
+	return func(s string) string {
+		if len(vars) == 0 && a.sessionVariables.len() == 0 {
+			return s
+		}
+		return variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+			name := variablePattern.FindStringSubmatch(match)[1]
+			if value, found := a.sessionVariables.get(name); found {
+				return value
+			}
+			if value, found := vars[name]; found {
+				return value
+			}
+			return match
+		})
+	}
+}
+
+func (a *App) environmentNames() []string {
+	names := make([]string, 0, len(a.config.Environments))
+	for name := range a.config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwitchEnvironment activates the named environment. If name is empty,
+// the next environment in alphabetical order is selected. Unknown
+// environments are reported.
+func (a *App) SwitchEnvironment(g *gocui.Gui, name string) error {
+	if name != "" {
+		if _, found := a.config.Environments[name]; !found {
+			return a.OpenSaveResultView(fmt.Sprintf("Unknown environment: %v", name), g)
+		}
+	} else {
+		names := a.environmentNames()
+		if len(names) == 0 {
+			return nil
+		}
+		name = names[0]
+		for i, n := range names {
+			if n == a.config.General.Environment {
+				name = names[(i+1)%len(names)]
+				break
+			}
+		}
+	}
+	a.config.General.Environment = name
+	refreshStatusLine(a, g)
+	return nil
+}
@@ -17,7 +17,11 @@ type ResponseFormatter interface {
 
 func New(appConfig *config.Config, contentType string) ResponseFormatter {
 	ctype, _, err := mime.ParseMediaType(contentType)
-	if err == nil && appConfig.General.FormatJSON && (ctype == config.ContentTypes["json"] || strings.HasSuffix(ctype, "+json")) {
+	if err == nil && ctype == "text/event-stream" {
+		return &SSEFormatter{formatJSON: appConfig.General.FormatJSON}
+	} else if err == nil && appConfig.General.FormatJSON && ctype == GRAPHQL_RESPONSE_CONTENT_TYPE {
+		return &GraphQLFormatter{}
+	} else if err == nil && appConfig.General.FormatJSON && (ctype == config.ContentTypes["json"] || strings.HasSuffix(ctype, "+json")) {
 		return &jsonFormatter{}
 	} else if strings.Contains(contentType, "text/html") {
 		return &htmlFormatter{}
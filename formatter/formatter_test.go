@@ -2,7 +2,9 @@ package formatter
 
 import (
 	"bytes"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/asciimoo/wuzz/config"
 	"github.com/nwidger/jsoncolor"
@@ -75,6 +77,26 @@ func TestTitle(t *testing.T) {
 	if title != "[text]" {
 		t.Error("For text/html content type expected title ", title, " to be [text]")
 	}
+
+	//event stream
+	title = New(configFixture(true), "text/event-stream").Title()
+	if title != "[event-stream]" {
+		t.Error("For text/event-stream content type expected title ", title, " to be [event-stream]")
+	}
+
+	//graphql
+	title = New(configFixture(true), "application/graphql-response+json").Title()
+	if title != "[graphql]" {
+		t.Error("For application/graphql-response+json content type expected title ", title, " to be [graphql]")
+	}
+	title = NewGraphQL(configFixture(true), "application/json").Title()
+	if title != "[graphql]" {
+		t.Error("For GraphQL JSON responses expected title ", title, " to be [graphql]")
+	}
+	title = NewGraphQL(configFixture(true), "text/html").Title()
+	if title != "[html]" {
+		t.Error("For GraphQL HTML responses expected title ", title, " to be [html]")
+	}
 }
 
 func TestSearchable(t *testing.T) {
@@ -95,6 +117,64 @@ func TestSearchable(t *testing.T) {
 
 }
 
+func TestSSEParser(t *testing.T) {
+	p := &SSEParser{}
+	var events []SSEEvent
+	stream := ": comment\r\nretry: 5000\r\nid: 1\r\ndata: first\r\ndata: second\r\n\r\nevent: update\rdata:{\"a\": 1}\r\rid\n\ndata\n\nid: 2\n\ndata: incomplete"
+	// feed byte by byte to test events spanning chunks
+	for i := range stream {
+		events = append(events, p.Feed([]byte(stream[i:i+1]))...)
+	}
+	expected := []SSEEvent{
+		{Type: "message", ID: "1", Data: "first\nsecond", Retry: 5000 * time.Millisecond},
+		{Type: "update", ID: "1", Data: "{\"a\": 1}"},
+		{Type: "message", ID: "", Data: ""},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		if events[i] != e {
+			t.Errorf("Expected event %d to eq %v, got %v", i, e, events[i])
+		}
+	}
+	if p.LastEventID != "2" {
+		t.Error("Expected last event id to be 2, got " + p.LastEventID)
+	}
+	if p.Retry != 5*time.Second {
+		t.Error("Expected retry to be 5s, got ", p.Retry)
+	}
+}
+
+func TestSSEFormatter(t *testing.T) {
+	f := New(configFixture(false), "text/event-stream; charset=utf-8").(*SSEFormatter)
+	body := []byte("event: ping\ndata: {}\n\n")
+	if events := f.Update(body); len(events) != 1 || events[0].Received.IsZero() {
+		t.Fatal("Expected a timestamped event, got ", events)
+	}
+	if events := f.Update(body); len(events) != 0 {
+		t.Error("Expected no new events, got ", events)
+	}
+	body = append(body, "id: 7\ndata: hello\n\n"...)
+	if events := f.Update(body); len(events) != 1 || events[0].Data != "hello" {
+		t.Error("Expected the hello event, got ", events)
+	}
+	if f.LastEventID() != "7" || f.Retry() != DEFAULT_SSE_RETRY {
+		t.Error("Unexpected stream state ", f.LastEventID(), f.Retry())
+	}
+
+	var buf bytes.Buffer
+	f.Format(&buf, body)
+	if !strings.Contains(buf.String(), "ping") || !strings.Contains(buf.String(), "hello") {
+		t.Error("Expected both events to be formatted, got " + buf.String())
+	}
+
+	results, err := f.Search("hel+o", body)
+	if err != nil || len(results) != 1 || !strings.Contains(results[0], "hello") {
+		t.Error("Expected search to return the hello event, got ", results, err)
+	}
+}
+
 func configFixture(jsonEnabled bool) *config.Config {
 	return &config.Config{
 		General: config.GeneralOptions{
@@ -102,3 +182,32 @@ func configFixture(jsonEnabled bool) *config.Config {
 		},
 	}
 }
+
+func TestGraphQLFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	body := `{"errors":[{"message":"Cannot query field \"nme\"","path":["user",0,"nme"],"locations":[{"line":3,"column":5}]}],"data":{"user":null}}`
+	if err := NewGraphQL(configFixture(true), "application/json").Format(&buf, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	errorsAt := strings.Index(out, "Errors (1)")
+	dataAt := strings.Index(out, "Data")
+	if errorsAt < 0 || dataAt < errorsAt {
+		t.Fatalf("Expected errors before data, got %q", out)
+	}
+	for _, s := range []string{`Cannot query field "nme"`, "user.0.nme", "3:5"} {
+		if !strings.Contains(out[:dataAt], s) {
+			t.Errorf("Expected %q in the errors of %q", s, out)
+		}
+	}
+	if !strings.Contains(out[dataAt:], "user") {
+		t.Errorf("Expected the data in %q", out)
+	}
+
+	// other JSON documents are formatted as JSON
+	buf.Reset()
+	NewGraphQL(configFixture(true), "application/json").Format(&buf, []byte(`{"json": "some value"}`))
+	if strings.Contains(buf.String(), "Data") {
+		t.Errorf("Expected plain JSON output, got %q", buf.String())
+	}
+}
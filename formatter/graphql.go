@@ -0,0 +1,86 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/asciimoo/wuzz/config"
+)
+
+const GRAPHQL_RESPONSE_CONTENT_TYPE = "application/graphql-response+json"
+
+type graphQLError struct {
+	Message   string
+	Path      []interface{}
+	Locations []struct {
+		Line   int
+		Column int
+	}
+	Extensions json.RawMessage
+}
+
+// GraphQLFormatter displays the errors of a GraphQL response separately
+// from its data
+type GraphQLFormatter struct {
+	jsonFormatter
+}
+
+// NewGraphQL creates the formatter of a response to a GraphQL request,
+// responses which are not JSON get the formatter of their content type
+func NewGraphQL(appConfig *config.Config, contentType string) ResponseFormatter {
+	f := New(appConfig, contentType)
+	if _, isJSON := f.(*jsonFormatter); isJSON {
+		return &GraphQLFormatter{}
+	}
+	return f
+}
+
+func (f *GraphQLFormatter) Format(writer io.Writer, data []byte) error {
+	var response struct {
+		Data       json.RawMessage
+		Errors     []graphQLError
+		Extensions json.RawMessage
+	}
+	if err := json.Unmarshal(data, &response); err != nil || (response.Data == nil && response.Errors == nil) {
+		return f.jsonFormatter.Format(writer, data)
+	}
+	if len(response.Errors) > 0 {
+		fmt.Fprintf(writer, "\x1b[0;31mErrors (%d)\x1b[0;0m\n", len(response.Errors))
+		for _, e := range response.Errors {
+			fmt.Fprintf(writer, "\x1b[0;31m- %v\x1b[0;0m", e.Message)
+			if len(e.Path) > 0 {
+				path := make([]string, len(e.Path))
+				for i, p := range e.Path {
+					path[i] = fmt.Sprint(p)
+				}
+				fmt.Fprintf(writer, " \x1b[0;33mpath:\x1b[0;0m %v", strings.Join(path, "."))
+			}
+			for _, l := range e.Locations {
+				fmt.Fprintf(writer, " \x1b[0;33mline:\x1b[0;0m %d:%d", l.Line, l.Column)
+			}
+			fmt.Fprintln(writer)
+			if len(e.Extensions) > 0 {
+				fmt.Fprintf(writer, "  \x1b[0;33mextensions:\x1b[0;0m %s\n", e.Extensions)
+			}
+		}
+		fmt.Fprintln(writer)
+	}
+	fmt.Fprint(writer, "\x1b[0;32mData\x1b[0;0m\n")
+	if response.Data == nil {
+		response.Data = json.RawMessage("null")
+	}
+	if err := f.jsonFormatter.Format(writer, response.Data); err != nil {
+		return err
+	}
+	if len(response.Extensions) > 0 {
+		fmt.Fprint(writer, "\n\n\x1b[0;32mExtensions\x1b[0;0m\n")
+		return f.jsonFormatter.Format(writer, response.Extensions)
+	}
+	return nil
+}
+
+func (f *GraphQLFormatter) Title() string {
+	return "[graphql]"
+}
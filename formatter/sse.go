@@ -0,0 +1,241 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nwidger/jsoncolor"
+)
+
+const DEFAULT_SSE_RETRY = 3 * time.Second
+
+// SSEEvent is a dispatched event of a text/event-stream response
+type SSEEvent struct {
+	Received time.Time
+	Type     string
+	ID       string
+	Data     string
+	Retry    time.Duration `json:",omitempty"`
+}
+
+// SSEParser splits an event stream into events, see
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+// The stream can be fed in arbitrary chunks.
+type SSEParser struct {
+	LastEventID string
+	// reconnection time requested by the server, 0 if not set
+	Retry time.Duration
+
+	buf       []byte
+	skipLF    bool
+	eventType string
+	data      []string
+	retry     time.Duration
+}
+
+// Feed parses a chunk of the stream and returns the completed events
+func (p *SSEParser) Feed(chunk []byte) []SSEEvent {
+	p.buf = append(p.buf, chunk...)
+	events := make([]SSEEvent, 0, 4)
+	for {
+		// a CR at the end of the previous chunk may be followed by LF
+		if p.skipLF && len(p.buf) > 0 {
+			if p.buf[0] == '\n' {
+				p.buf = p.buf[1:]
+			}
+			p.skipLF = false
+		}
+		i := bytes.IndexAny(p.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := string(p.buf[:i])
+		if p.buf[i] == '\r' {
+			p.skipLF = true
+		}
+		p.buf = p.buf[i+1:]
+		if e, dispatched := p.processLine(line); dispatched {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (p *SSEParser) processLine(line string) (SSEEvent, bool) {
+	if line == "" {
+		return p.dispatch()
+	}
+	// comment
+	if line[0] == ':' {
+		return SSEEvent{}, false
+	}
+	field, value := line, ""
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		field = line[:i]
+		value = strings.TrimPrefix(line[i+1:], " ")
+	}
+	switch field {
+	case "event":
+		p.eventType = value
+	case "data":
+		p.data = append(p.data, value)
+	case "id":
+		if !strings.ContainsRune(value, 0) {
+			p.LastEventID = value
+		}
+	case "retry":
+		if ms, err := strconv.ParseUint(value, 10, 32); err == nil {
+			p.retry = time.Duration(ms) * time.Millisecond
+			p.Retry = p.retry
+		}
+	}
+	return SSEEvent{}, false
+}
+
+func (p *SSEParser) dispatch() (SSEEvent, bool) {
+	defer func() {
+		p.eventType = ""
+		p.data = nil
+		p.retry = 0
+	}()
+	if p.data == nil {
+		return SSEEvent{}, false
+	}
+	e := SSEEvent{
+		Type:  p.eventType,
+		ID:    p.LastEventID,
+		Data:  strings.Join(p.data, "\n"),
+		Retry: p.retry,
+	}
+	if e.Type == "" {
+		e.Type = "message"
+	}
+	return e, true
+}
+
+// Reset drops the incomplete event, it is called when the connection is
+// closed
+func (p *SSEParser) Reset() {
+	p.buf = nil
+	p.skipLF = false
+	p.eventType = ""
+	p.data = nil
+	p.retry = 0
+}
+
+// SSEFormatter formats text/event-stream responses as a list of events.
+// Events are stamped with the time they are parsed, so a streamed body
+// should be passed to Update whenever it grows.
+type SSEFormatter struct {
+	Events     []SSEEvent
+	parser     SSEParser
+	parsed     int
+	formatJSON bool
+}
+
+// Update parses the part of the body which was not seen yet and returns
+// the new events
+func (f *SSEFormatter) Update(data []byte) []SSEEvent {
+	if len(data) <= f.parsed {
+		return nil
+	}
+	events := f.parser.Feed(data[f.parsed:])
+	f.parsed = len(data)
+	now := time.Now()
+	for i := range events {
+		events[i].Received = now
+	}
+	f.Events = append(f.Events, events...)
+	return events
+}
+
+// Restore sets the previously received events of data
+func (f *SSEFormatter) Restore(events []SSEEvent, data []byte) {
+	f.Events = events
+	f.parsed = len(data)
+	if len(events) > 0 {
+		f.parser.LastEventID = events[len(events)-1].ID
+	}
+}
+
+// Reset drops the incomplete event after the connection is closed
+func (f *SSEFormatter) Reset() {
+	f.parser.Reset()
+}
+
+func (f *SSEFormatter) LastEventID() string {
+	return f.parser.LastEventID
+}
+
+// Retry returns the reconnection time of the stream
+func (f *SSEFormatter) Retry() time.Duration {
+	if f.parser.Retry == 0 {
+		return DEFAULT_SSE_RETRY
+	}
+	return f.parser.Retry
+}
+
+func (f *SSEFormatter) Format(writer io.Writer, data []byte) error {
+	f.Update(data)
+	for _, e := range f.Events {
+		f.FormatEvent(writer, e)
+	}
+	return nil
+}
+
+// FormatEvent writes an event with its receive time, JSON data is pretty
+// printed
+func (f *SSEFormatter) FormatEvent(writer io.Writer, e SSEEvent) {
+	fmt.Fprintf(writer, "\x1b[0;36m[%v]\x1b[0;0m \x1b[0;33mevent:\x1b[0;0m %v", e.Received.Format("15:04:05.000"), e.Type)
+	if e.ID != "" {
+		fmt.Fprintf(writer, " \x1b[0;33mid:\x1b[0;0m %v", e.ID)
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(writer, " \x1b[0;33mretry:\x1b[0;0m %v", e.Retry)
+	}
+	fmt.Fprintln(writer)
+	data := []byte(e.Data)
+	if f.formatJSON && json.Valid(data) {
+		jsonFormatter := jsoncolor.NewFormatter()
+		jsonFormatter.Indent = "  "
+		buf := bytes.NewBuffer(make([]byte, 0, len(data)))
+		if err := jsonFormatter.Format(buf, data); err == nil {
+			data = buf.Bytes()
+		}
+	}
+	fmt.Fprintf(writer, "%s\n\n", bytes.TrimRight(data, "\n"))
+}
+
+func (f *SSEFormatter) Title() string {
+	return "[event-stream]"
+}
+
+func (f *SSEFormatter) Searchable() bool {
+	return true
+}
+
+// Search returns the events whose type, id or data matches the regular
+// expression
+func (f *SSEFormatter) Search(q string, body []byte) ([]string, error) {
+	search_re, err := regexp.Compile(q)
+	if err != nil {
+		return nil, err
+	}
+	f.Update(body)
+	ret := make([]string, 0, 16)
+	for _, e := range f.Events {
+		if !search_re.MatchString(e.Type) && !search_re.MatchString(e.ID) && !search_re.MatchString(e.Data) {
+			continue
+		}
+		buf := &strings.Builder{}
+		f.FormatEvent(buf, e)
+		ret = append(ret, strings.TrimRight(buf.String(), "\n"))
+	}
+	return ret, nil
+}
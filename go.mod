@@ -9,12 +9,14 @@ require (
 	github.com/PuerkitoBio/goquery v1.10.3
 	github.com/alessio/shellescape v1.4.2
 	github.com/awesome-gocui/gocui v1.1.0
+	github.com/bufbuild/protocompile v0.14.1
 	github.com/mattn/go-runewidth v0.0.19
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/nwidger/jsoncolor v0.3.2
 	github.com/tidwall/gjson v1.18.0
 	github.com/x86kernel/htmlcolor v0.0.0-20190529101448-c589f58466d0
 	golang.org/x/net v0.46.0
+	google.golang.org/protobuf v1.36.10
 )
 
 require (
@@ -25,7 +27,7 @@ require (
 	github.com/fatih/color v1.18.0 // indirect
 	github.com/gdamore/encoding v1.0.1 // indirect
 	github.com/gdamore/tcell/v2 v2.9.0 // indirect
-	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.3.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/asciimoo/wuzz/config"
+	"github.com/asciimoo/wuzz/request"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+const GRAPHQL_INTROSPECTION_QUERY = `query IntrospectionQuery {
+  __schema {
+    types {
+      name
+      fields(includeDeprecated: true) { name }
+      inputFields { name }
+      enumValues(includeDeprecated: true) { name }
+    }
+  }
+}`
+
+var GRAPHQL_KEYWORDS = []string{
+	"query",
+	"mutation",
+	"subscription",
+	"fragment",
+	"on",
+}
+
+// graphQLOperationPattern matches the names of operation definitions
+var graphQLOperationPattern = regexp.MustCompile(`(?m)^[ \t]*(?:query|mutation|subscription)[ \t]+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// graphQLMode holds the schema of the GraphQL endpoint while the data view
+// is edited as a GraphQL query
+type graphQLMode struct {
+	schemaURL string
+	// shown in the title of the query view
+	schemaState string
+	completions []string
+	editor      gocui.Editor
+}
+
+type graphQLRequest struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	OperationName string          `json:"operationName,omitempty"`
+}
+
+// graphQLBody wraps a query and its JSON variables into the POST body of
+// a GraphQL request
+func graphQLBody(query, variables, operationName string) ([]byte, error) {
+	r := graphQLRequest{Query: query, OperationName: operationName}
+	if strings.TrimSpace(variables) != "" {
+		var vars map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(variables), &vars); err != nil {
+			return nil, fmt.Errorf("Invalid variables: %v", err)
+		}
+		r.Variables = json.RawMessage(variables)
+	}
+	return json.Marshal(r)
+}
+
+// graphQLOperationName returns the name of the operation defined at the
+// given line of a query document, or of the first operation if the line
+// precedes every definition
+func graphQLOperationName(query string, line int) string {
+	name := ""
+	for _, m := range graphQLOperationPattern.FindAllStringSubmatchIndex(query, -1) {
+		if name != "" && strings.Count(query[:m[0]], "\n") > line {
+			break
+		}
+		name = query[m[2]:m[3]]
+	}
+	return name
+}
+
+// introspectGraphQLSchema fetches the names of the types, fields and enum
+// values of a GraphQL schema
+func introspectGraphQLSchema(ctx context.Context, client *request.Client, endpoint, headers string) ([]string, error) {
+	body, err := graphQLBody(GRAPHQL_INTROSPECTION_QUERY, "", "IntrospectionQuery")
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Send(ctx, &request.Request{
+		Method:  http.MethodPost,
+		URL:     endpoint,
+		Headers: headers + "\nContent-Type: " + config.ContentTypes["json"],
+		Body:    body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	status := fmt.Sprintf("%v %v", response.StatusCode, http.StatusText(response.StatusCode))
+
+	var result struct {
+		Data struct {
+			Schema struct {
+				Types []struct {
+					Name        string
+					Fields      []struct{ Name string }
+					InputFields []struct{ Name string }
+					EnumValues  []struct{ Name string }
+				}
+			} `json:"__schema"`
+		}
+		Errors []struct{ Message string }
+	}
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, fmt.Errorf("%v: %v", status, err)
+	}
+	types := result.Data.Schema.Types
+	if len(types) == 0 {
+		if len(result.Errors) > 0 {
+			return nil, errors.New(result.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("%v: empty schema", status)
+	}
+
+	names := make(map[string]bool)
+	for _, t := range types {
+		// skip the introspection types
+		if strings.HasPrefix(t.Name, "__") {
+			continue
+		}
+		names[t.Name] = true
+		for _, f := range t.Fields {
+			names[f.Name] = true
+		}
+		for _, f := range t.InputFields {
+			names[f.Name] = true
+		}
+		for _, v := range t.EnumValues {
+			names[v.Name] = true
+		}
+	}
+	for _, k := range GRAPHQL_KEYWORDS {
+		names[k] = true
+	}
+	completions := make([]string, 0, len(names))
+	for name := range names {
+		completions = append(completions, name)
+	}
+	sort.Strings(completions)
+	return completions, nil
+}
+
+// fetchGraphQLSchema loads the completions of the query view in the
+// background, the variables of the headers are substituted
+func (a *App) fetchGraphQLSchema(g *gocui.Gui, endpoint, headers string) {
+	mode := a.graphQL
+	mode.schemaURL = endpoint
+	mode.schemaState = "loading schema"
+	a.Layout(g)
+	client := &request.Client{Builder: a.requestBuilder(), HTTPClient: CLIENT}
+	go func() {
+		ctx := context.Background()
+		if timeout := a.config.General.Timeout.Duration; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		completions, err := introspectGraphQLSchema(ctx, client, endpoint, headers)
+		g.UpdateAsync(func(g *gocui.Gui) error {
+			if a.graphQL != mode || mode.schemaURL != endpoint {
+				return nil
+			}
+			if err != nil {
+				mode.schemaState = "no schema: " + err.Error()
+			} else {
+				mode.completions = completions
+				mode.schemaState = fmt.Sprintf("schema: %d names", len(completions))
+			}
+			return a.Layout(g)
+		})
+	}()
+}
+
+// ToggleGraphQL switches the data view between request data and GraphQL
+// queries, the schema of the URL is fetched when the mode is enabled
+func (a *App) ToggleGraphQL(g *gocui.Gui, _ *gocui.View) error {
+	if a.graphQL != nil {
+		a.setGraphQLMode(g, false)
+		return nil
+	}
+	a.setGraphQLMode(g, true)
+	a.fetchGraphQLSchema(g, a.substituteVariables(getViewValue(g, URL_VIEW)), getViewValue(g, REQUEST_HEADERS_VIEW))
+	return nil
+}
+
+// setGraphQLMode shows or hides the variables view, GraphQL requests are
+// always POST requests
+func (a *App) setGraphQLMode(g *gocui.Gui, enabled bool) {
+	if enabled == (a.graphQL != nil) {
+		return
+	}
+	if enabled {
+		mode := &graphQLMode{}
+		mode.editor = &AutocompleteEditor{&defaultEditor, func(str string) []string {
+			return completeFromSlice(str, mode.completions)
+		}, []string{}, false}
+		a.graphQL = mode
+		for i, name := range VIEWS {
+			if name == REQUEST_DATA_VIEW {
+				VIEWS = append(VIEWS[:i+1], append([]string{GRAPHQL_VARIABLES_VIEW}, VIEWS[i+1:]...)...)
+				if a.viewIndex > i {
+					a.viewIndex++
+				}
+				break
+			}
+		}
+		vmethod, _ := g.View(REQUEST_METHOD_VIEW)
+		setViewTextAndCursor(vmethod, http.MethodPost)
+		a.Layout(g)
+		return
+	}
+
+	a.graphQL = nil
+	focused := g.CurrentView() != nil && g.CurrentView().Name() == GRAPHQL_VARIABLES_VIEW
+	for i, name := range VIEWS {
+		if name == GRAPHQL_VARIABLES_VIEW {
+			VIEWS = append(VIEWS[:i], VIEWS[i+1:]...)
+			if a.viewIndex > i || a.viewIndex >= len(VIEWS) {
+				a.viewIndex--
+			}
+			break
+		}
+	}
+	g.DeleteView(GRAPHQL_VARIABLES_VIEW)
+	if v, err := g.View(REQUEST_DATA_VIEW); err == nil {
+		v.Title = VIEW_PROPERTIES[REQUEST_DATA_VIEW].title
+		v.Editor = VIEW_PROPERTIES[REQUEST_DATA_VIEW].editor
+	}
+	closeAutocomplete(g)
+	if focused {
+		a.setView(g)
+	}
+	a.Layout(g)
+}
+
+// layoutGraphQL splits the data view into the query and the variables
+// views while the GraphQL mode is enabled
+func (a *App) layoutGraphQL(g *gocui.Gui) error {
+	if a.graphQL == nil {
+		return nil
+	}
+	vdata, err := setViewAt(g, REQUEST_DATA_VIEW, GRAPHQL_QUERY_POSITION)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	vdata.Title = "GraphQL query"
+	if a.graphQL.schemaState != "" {
+		vdata.Title += " [" + a.graphQL.schemaState + "]"
+	}
+	vdata.Editor = a.graphQL.editor
+	if _, err := setViewAt(g, REQUEST_HEADERS_VIEW, GRAPHQL_HEADERS_POSITION); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	if v, err := setView(g, GRAPHQL_VARIABLES_VIEW); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		setViewProperties(v, GRAPHQL_VARIABLES_VIEW)
+	}
+	return nil
+}
+
+// viewCursorLine returns the line of the cursor in the buffer of a view
+func viewCursorLine(g *gocui.Gui, name string) int {
+	v, err := g.View(name)
+	if err != nil {
+		return 0
+	}
+	_, cy := v.Cursor()
+	_, oy := v.Origin()
+	return cy + oy
+}
+
+// body returns the POST body of a request, the query and variables of
+// GraphQL requests are wrapped into a JSON object
+func (r Request) body() string {
+	if !r.GraphQL {
+		return r.Data
+	}
+	data, err := graphQLBody(r.Data, r.Variables, r.OperationName)
+	if err != nil {
+		return r.Data
+	}
+	return string(data)
+}
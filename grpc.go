@@ -0,0 +1,902 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/bufbuild/protocompile"
+	"github.com/mitchellh/go-homedir"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	GRPC_CONTENT_TYPE     = "application/grpc"
+	GRPC_WEB_CONTENT_TYPE = "application/grpc-web+proto"
+
+	GRPC_REFLECTION_SERVICE       = "grpc.reflection.v1.ServerReflection"
+	GRPC_REFLECTION_ALPHA_SERVICE = "grpc.reflection.v1alpha.ServerReflection"
+
+	GRPC_UNIMPLEMENTED = 12
+)
+
+var GRPC_STATUS_CODES = []string{
+	"OK",
+	"CANCELLED",
+	"UNKNOWN",
+	"INVALID_ARGUMENT",
+	"DEADLINE_EXCEEDED",
+	"NOT_FOUND",
+	"ALREADY_EXISTS",
+	"PERMISSION_DENIED",
+	"RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION",
+	"ABORTED",
+	"OUT_OF_RANGE",
+	"UNIMPLEMENTED",
+	"INTERNAL",
+	"UNAVAILABLE",
+	"DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// GRPC_CLIENT sends gRPC calls over HTTP/2, grpc:// URLs use cleartext
+// HTTP/2 with prior knowledge. gRPC-web calls are sent by CLIENT.
+var GRPC_CLIENT = &http.Client{
+	CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// grpcDescriptors holds the services of the configured .proto files and
+// the files fetched with server reflection
+type grpcDescriptors struct {
+	sync.Mutex
+	config *protoregistry.Files
+	// reflected file descriptors by host
+	hosts map[string]map[string]*descriptorpb.FileDescriptorProto
+}
+
+type grpcStatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *grpcStatusError) Error() string {
+	return fmt.Sprintf("gRPC status %v: %v", grpcStatusName(e.Code), e.Message)
+}
+
+// grpcCall is the method called by a request to a grpc:// or grpcs:// URL
+type grpcCall struct {
+	method protoreflect.MethodDescriptor
+	types  *dynamicpb.Types
+	web    bool
+}
+
+func isGRPCURL(u string) bool {
+	return strings.HasPrefix(u, "grpc://") || strings.HasPrefix(u, "grpcs://")
+}
+
+func grpcStatusName(code int) string {
+	if code >= 0 && code < len(GRPC_STATUS_CODES) {
+		return fmt.Sprintf("%d %v", code, GRPC_STATUS_CODES[code])
+	}
+	return strconv.Itoa(code)
+}
+
+// initGRPCTransport derives the HTTP/2 only transport of gRPC calls from
+// TRANSPORT, so proxy and TLS settings apply to both
+func initGRPCTransport() {
+	transport := TRANSPORT.Clone()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetHTTP2(true)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	GRPC_CLIENT.Transport = transport
+}
+
+// loadProtoFiles compiles the .proto files of the config. Without import
+// paths, imports are resolved relative to the directory of each file.
+func loadProtoFiles(files, importPaths []string) (*protoregistry.Files, error) {
+	registry := &protoregistry.Files{}
+	if len(files) == 0 {
+		return registry, nil
+	}
+	names := make([]string, len(files))
+	paths := make([]string, 0, len(importPaths)+len(files))
+	for _, p := range importPaths {
+		p, err := homedir.Expand(p)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	for i, f := range files {
+		f, err := homedir.Expand(f)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = f
+		if len(importPaths) == 0 {
+			paths = append(paths, filepath.Dir(f))
+			names[i] = filepath.Base(f)
+		}
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: paths}),
+	}
+	compiled, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load .proto files: %v", err)
+	}
+	for _, f := range compiled {
+		if err := registerProtoFile(registry, f); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// registerProtoFile adds a file and its imports to the registry
+func registerProtoFile(registry *protoregistry.Files, f protoreflect.FileDescriptor) error {
+	if _, err := registry.FindFileByPath(f.Path()); err == nil {
+		return nil
+	}
+	if err := registry.RegisterFile(f); err != nil {
+		return err
+	}
+	imports := f.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerProtoFile(registry, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *grpcDescriptors) setConfig(files *protoregistry.Files) {
+	d.Lock()
+	defer d.Unlock()
+	d.config = files
+}
+
+func (d *grpcDescriptors) configFiles() *protoregistry.Files {
+	d.Lock()
+	defer d.Unlock()
+	if d.config == nil {
+		return &protoregistry.Files{}
+	}
+	return d.config
+}
+
+// reflectedFiles returns a registry of the reflected files of host
+func (d *grpcDescriptors) reflectedFiles(host string) (*protoregistry.Files, error) {
+	d.Lock()
+	defer d.Unlock()
+	files := make([]*descriptorpb.FileDescriptorProto, 0, len(d.hosts[host]))
+	for _, f := range d.hosts[host] {
+		files = append(files, f)
+	}
+	return protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: files})
+}
+
+func (d *grpcDescriptors) addReflectedFiles(host string, files map[string]*descriptorpb.FileDescriptorProto) {
+	d.Lock()
+	defer d.Unlock()
+	if d.hosts == nil {
+		d.hosts = make(map[string]map[string]*descriptorpb.FileDescriptorProto)
+	}
+	if d.hosts[host] == nil {
+		d.hosts[host] = make(map[string]*descriptorpb.FileDescriptorProto, len(files))
+	}
+	for name, f := range files {
+		d.hosts[host][name] = f
+	}
+}
+
+// grpcTarget converts a grpc:// or grpcs:// URL to the URL of the HTTP
+// request
+func grpcTarget(u *url.URL) *url.URL {
+	target := *u
+	if u.Scheme == "grpcs" {
+		target.Scheme = "https"
+	} else {
+		target.Scheme = "http"
+	}
+	return &target
+}
+
+// setGRPCHeaders sets the protocol headers of a call, the other headers
+// are sent as metadata. gRPC-web is used if the Content-Type header
+// selects it.
+func setGRPCHeaders(headers http.Header) (web bool) {
+	contentType := headers.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/grpc-web") {
+		headers.Set("X-Grpc-Web", "1")
+		return true
+	}
+	if contentType == "" {
+		headers.Set("Content-Type", GRPC_CONTENT_TYPE)
+	}
+	headers.Set("TE", "trailers")
+	return false
+}
+
+func isGRPCResponse(response *http.Response) bool {
+	return strings.HasPrefix(response.Header.Get("Content-Type"), GRPC_CONTENT_TYPE)
+}
+
+// encodeGRPCFrames prefixes the messages with the uncompressed flag and
+// their length
+func encodeGRPCFrames(messages [][]byte) []byte {
+	buf := &bytes.Buffer{}
+	for _, m := range messages {
+		var prefix [5]byte
+		binary.BigEndian.PutUint32(prefix[1:], uint32(len(m)))
+		buf.Write(prefix[:])
+		buf.Write(m)
+	}
+	return buf.Bytes()
+}
+
+// decodeGRPCFrames splits a response body into messages. The trailers of
+// gRPC-web responses are sent in a frame flagged with 0x80.
+func decodeGRPCFrames(body []byte, encoding string) (messages [][]byte, trailer http.Header, err error) {
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, nil, errors.New("Truncated gRPC frame")
+		}
+		flags := body[0]
+		length := binary.BigEndian.Uint32(body[1:5])
+		if uint64(len(body)-5) < uint64(length) {
+			return nil, nil, errors.New("Truncated gRPC frame")
+		}
+		data := body[5 : 5+length]
+		body = body[5+length:]
+		if flags&0x01 != 0 {
+			if encoding != "gzip" {
+				return nil, nil, fmt.Errorf("Unsupported gRPC message encoding: %q", encoding)
+			}
+			reader, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, nil, err
+			}
+			if data, err = io.ReadAll(reader); err != nil {
+				return nil, nil, err
+			}
+		}
+		if flags&0x80 != 0 {
+			header, err := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n")))).ReadMIMEHeader()
+			if err != nil && err != io.EOF {
+				return nil, nil, fmt.Errorf("Invalid gRPC-web trailers: %v", err)
+			}
+			trailer = http.Header(header)
+			continue
+		}
+		messages = append(messages, data)
+	}
+	return messages, trailer, nil
+}
+
+// grpcStatus returns the status of a call from the trailers, or from the
+// headers of trailers-only responses
+func grpcStatus(response *http.Response) (*grpcStatusError, bool) {
+	h := response.Trailer
+	if h.Get("Grpc-Status") == "" {
+		h = response.Header
+	}
+	if h.Get("Grpc-Status") == "" {
+		return nil, false
+	}
+	code, err := strconv.Atoi(h.Get("Grpc-Status"))
+	if err != nil {
+		code = 2
+	}
+	message, err := url.PathUnescape(h.Get("Grpc-Message"))
+	if err != nil {
+		message = h.Get("Grpc-Message")
+	}
+	return &grpcStatusError{code, message}, true
+}
+
+func formatGRPCStatus(response *http.Response) string {
+	status, found := grpcStatus(response)
+	if !found {
+		return ""
+	}
+	if status.Code == 0 {
+		return fmt.Sprintf("\x1b[0;32mgRPC status: %v\x1b[0;0m\n", grpcStatusName(status.Code))
+	}
+	return fmt.Sprintf("\x1b[0;31mgRPC status: %v: %v\x1b[0;0m\n", grpcStatusName(status.Code), status.Message)
+}
+
+// readGRPCResponse reads the messages of a response. The trailers of
+// gRPC-web responses are added to response.Trailer.
+func readGRPCResponse(response *http.Response) ([][]byte, error) {
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	messages, trailer, err := decodeGRPCFrames(body, response.Header.Get("Grpc-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	if trailer != nil {
+		if response.Trailer == nil {
+			response.Trailer = http.Header{}
+		}
+		for k, v := range trailer {
+			response.Trailer[k] = v
+		}
+	}
+	return messages, nil
+}
+
+// invokeGRPC calls a unary or server streaming method with an encoded
+// message and returns the encoded responses
+func invokeGRPC(ctx context.Context, target *url.URL, fullMethod string, headers http.Header, web bool, message []byte) ([][]byte, error) {
+	u := *target
+	u.Path = "/" + fullMethod
+	u.RawQuery = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(encodeGRPCFrames([][]byte{message})))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers.Clone()
+	client := GRPC_CLIENT
+	if web {
+		client = CLIENT
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if !isGRPCResponse(response) {
+		return nil, fmt.Errorf("Unexpected response: %v", response.Status)
+	}
+	messages, err := readGRPCResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	if status, found := grpcStatus(response); found && status.Code != 0 {
+		return nil, status
+	}
+	return messages, nil
+}
+
+// grpcReflectionClient queries the server reflection service, see
+// https://github.com/grpc/grpc/blob/master/doc/server-reflection.md
+type grpcReflectionClient struct {
+	target  *url.URL
+	headers http.Header
+	web     bool
+	// the supported reflection service, v1alpha is tried if v1 is
+	// unimplemented
+	service string
+}
+
+type reflectionResponse struct {
+	services []string
+	files    [][]byte
+}
+
+// consumeFields calls fn with the length delimited fields of an encoded
+// message, other fields are skipped
+func consumeFields(b []byte, fn func(protowire.Number, []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fn(num, v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// request sends a ServerReflectionRequest with a single string field set
+func (c *grpcReflectionClient) request(ctx context.Context, field protowire.Number, value string) (*reflectionResponse, error) {
+	request := protowire.AppendTag(nil, field, protowire.BytesType)
+	request = protowire.AppendString(request, value)
+	services := []string{c.service}
+	if c.service == "" {
+		services = []string{GRPC_REFLECTION_SERVICE, GRPC_REFLECTION_ALPHA_SERVICE}
+	}
+	var messages [][]byte
+	var err error
+	for _, service := range services {
+		messages, err = invokeGRPC(ctx, c.target, service+"/ServerReflectionInfo", c.headers, c.web, request)
+		if status, ok := err.(*grpcStatusError); ok && status.Code == GRPC_UNIMPLEMENTED {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.service = service
+		break
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Server reflection is not available: %v", err)
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("Empty server reflection response")
+	}
+
+	response := &reflectionResponse{}
+	var responseErr error
+	err = consumeFields(messages[0], func(num protowire.Number, v []byte) {
+		switch num {
+		case 4: // file_descriptor_response
+			consumeFields(v, func(num protowire.Number, v []byte) {
+				if num == 1 {
+					response.files = append(response.files, v)
+				}
+			})
+		case 6: // list_services_response
+			consumeFields(v, func(num protowire.Number, v []byte) {
+				if num != 1 {
+					return
+				}
+				consumeFields(v, func(num protowire.Number, v []byte) {
+					if num == 1 {
+						response.services = append(response.services, string(v))
+					}
+				})
+			})
+		case 7: // error_response
+			consumeFields(v, func(num protowire.Number, v []byte) {
+				if num == 2 {
+					responseErr = fmt.Errorf("Server reflection error: %s", v)
+				}
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, responseErr
+}
+
+func (c *grpcReflectionClient) listServices(ctx context.Context) ([]string, error) {
+	response, err := c.request(ctx, 7, "")
+	if err != nil {
+		return nil, err
+	}
+	return response.services, nil
+}
+
+// fileContainingSymbol fetches the file defining symbol and the
+// dependencies which are not known yet
+func (c *grpcReflectionClient) fileContainingSymbol(ctx context.Context, symbol string, files map[string]*descriptorpb.FileDescriptorProto) error {
+	response, err := c.request(ctx, 4, symbol)
+	if err != nil {
+		return err
+	}
+	pending := response.files
+	for len(pending) > 0 {
+		f := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(pending[0], f); err != nil {
+			return fmt.Errorf("Invalid file descriptor: %v", err)
+		}
+		pending = pending[1:]
+		files[f.GetName()] = f
+		for _, dep := range f.GetDependency() {
+			if _, found := files[dep]; found {
+				continue
+			}
+			response, err := c.request(ctx, 3, dep)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, response.files...)
+		}
+	}
+	return nil
+}
+
+func (a *App) newGRPCReflectionClient(target *url.URL, headers http.Header, web bool) *grpcReflectionClient {
+	headers = headers.Clone()
+	if web {
+		headers.Set("Content-Type", GRPC_WEB_CONTENT_TYPE)
+	}
+	return &grpcReflectionClient{target: target, headers: headers, web: web}
+}
+
+func splitGRPCMethod(fullMethod string) (protoreflect.FullName, protoreflect.Name, error) {
+	i := strings.LastIndex(fullMethod, "/")
+	if i < 0 {
+		return "", "", errors.New("The URL path must be /package.Service/Method")
+	}
+	return protoreflect.FullName(fullMethod[:i]), protoreflect.Name(fullMethod[i+1:]), nil
+}
+
+func findGRPCMethod(files *protoregistry.Files, service protoreflect.FullName, method protoreflect.Name, web bool) *grpcCall {
+	d, err := files.FindDescriptorByName(service)
+	if err != nil {
+		return nil
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	md := sd.Methods().ByName(method)
+	if md == nil {
+		return nil
+	}
+	return &grpcCall{method: md, types: dynamicpb.NewTypes(files), web: web}
+}
+
+// cachedGRPCMethod finds the descriptor of a "package.Service/Method" in
+// the configured .proto files or the files already reflected from the
+// host, it sends no request
+func (a *App) cachedGRPCMethod(target *url.URL, web bool, fullMethod string) *grpcCall {
+	service, method, err := splitGRPCMethod(fullMethod)
+	if err != nil {
+		return nil
+	}
+	if call := findGRPCMethod(a.grpc.configFiles(), service, method, web); call != nil {
+		return call
+	}
+	if files, err := a.grpc.reflectedFiles(target.Host); err == nil {
+		return findGRPCMethod(files, service, method, web)
+	}
+	return nil
+}
+
+// grpcMethod finds the descriptor of a "package.Service/Method" in the
+// configured .proto files or with the server reflection of the host
+func (a *App) grpcMethod(ctx context.Context, target *url.URL, headers http.Header, web bool, fullMethod string) (*grpcCall, error) {
+	service, method, err := splitGRPCMethod(fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if call := a.cachedGRPCMethod(target, web, fullMethod); call != nil {
+		return call, nil
+	}
+	reflection := a.newGRPCReflectionClient(target, headers, web)
+	fetched := make(map[string]*descriptorpb.FileDescriptorProto)
+	if err := reflection.fileContainingSymbol(ctx, string(service), fetched); err != nil {
+		return nil, err
+	}
+	a.grpc.addReflectedFiles(target.Host, fetched)
+	files, err := a.grpc.reflectedFiles(target.Host)
+	if err != nil {
+		return nil, err
+	}
+	if call := findGRPCMethod(files, service, method, web); call != nil {
+		return call, nil
+	}
+	return nil, fmt.Errorf("Unknown gRPC method: %v", fullMethod)
+}
+
+// newGRPCCall prepares the body of a gRPC call to the method of the URL:
+// the JSON message of the data view is encoded with the input type of the
+// method. Client streaming methods accept a JSON array of messages.
+func (a *App) newGRPCCall(ctx context.Context, u *url.URL, headers http.Header, data string) (*grpcCall, []byte, error) {
+	headers = headers.Clone()
+	web := setGRPCHeaders(headers)
+	call, err := a.grpcMethod(ctx, grpcTarget(u), headers, web, strings.TrimPrefix(u.Path, "/"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inputs := []json.RawMessage{json.RawMessage(data)}
+	if strings.TrimSpace(data) == "" {
+		inputs[0] = json.RawMessage("{}")
+	} else if call.method.IsStreamingClient() && strings.HasPrefix(strings.TrimSpace(data), "[") {
+		if err := json.Unmarshal([]byte(data), &inputs); err != nil {
+			return nil, nil, err
+		}
+	}
+	messages := make([][]byte, len(inputs))
+	for i, input := range inputs {
+		m := dynamicpb.NewMessage(call.method.Input())
+		if err := (protojson.UnmarshalOptions{Resolver: call.types}).Unmarshal(input, m); err != nil {
+			return nil, nil, fmt.Errorf("Invalid %v message: %v", call.method.Input().FullName(), err)
+		}
+		if messages[i], err = proto.Marshal(m); err != nil {
+			return nil, nil, err
+		}
+	}
+	return call, encodeGRPCFrames(messages), nil
+}
+
+// setGRPCRequest converts a request to a grpc:// or grpcs:// URL to the
+// HTTP request of the call
+func setGRPCRequest(req *http.Request) {
+	setGRPCHeaders(req.Header)
+	req.URL = grpcTarget(req.URL)
+}
+
+// decodeResponse reads the response messages of a call and returns them
+// as JSON, streamed responses are returned as an array
+func (c *grpcCall) decodeResponse(response *http.Response) ([]byte, error) {
+	messages, err := readGRPCResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]string, len(messages))
+	for i, message := range messages {
+		m := dynamicpb.NewMessage(c.method.Output())
+		if err := (proto.UnmarshalOptions{Resolver: c.types}).Unmarshal(message, m); err != nil {
+			return nil, fmt.Errorf("Invalid %v message: %v", c.method.Output().FullName(), err)
+		}
+		data, err := protojson.MarshalOptions{Resolver: c.types}.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = string(data)
+	}
+	body := strings.Join(decoded, ",")
+	if len(decoded) > 1 {
+		body = "[" + body + "]"
+	}
+	return indentJSON([]byte(body)), nil
+}
+
+// indentJSON normalizes the whitespace of the protojson output
+func indentJSON(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, data, "", "  "); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// grpcMethodTemplate returns the JSON of the input message of a method
+// with every field set to its default value
+func grpcMethodTemplate(call *grpcCall) string {
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true, Resolver: call.types}.Marshal(dynamicpb.NewMessage(call.method.Input()))
+	if err != nil {
+		return "{}"
+	}
+	return string(indentJSON(data))
+}
+
+// listGRPCMethods returns the methods of the configured .proto files and
+// of the services listed by the server reflection of grpc:// and grpcs://
+// URLs
+func (a *App) listGRPCMethods(ctx context.Context, rawURL string, headers http.Header) ([]string, error) {
+	methods := make([]string, 0, 16)
+	addMethods := func(sd protoreflect.ServiceDescriptor) {
+		for i := 0; i < sd.Methods().Len(); i++ {
+			methods = append(methods, fmt.Sprintf("%v/%v", sd.FullName(), sd.Methods().Get(i).Name()))
+		}
+	}
+	a.grpc.configFiles().RangeFiles(func(f protoreflect.FileDescriptor) bool {
+		for i := 0; i < f.Services().Len(); i++ {
+			addMethods(f.Services().Get(i))
+		}
+		return true
+	})
+
+	var err error
+	if u, parseErr := url.Parse(rawURL); parseErr == nil && isGRPCURL(rawURL) {
+		err = func() error {
+			web := setGRPCHeaders(headers)
+			target := grpcTarget(u)
+			reflection := a.newGRPCReflectionClient(target, headers, web)
+			services, err := reflection.listServices(ctx)
+			if err != nil {
+				return err
+			}
+			fetched := make(map[string]*descriptorpb.FileDescriptorProto)
+			for _, service := range services {
+				if service == GRPC_REFLECTION_SERVICE || service == GRPC_REFLECTION_ALPHA_SERVICE {
+					continue
+				}
+				if err := reflection.fileContainingSymbol(ctx, service, fetched); err != nil {
+					return err
+				}
+			}
+			a.grpc.addReflectedFiles(target.Host, fetched)
+			files, err := a.grpc.reflectedFiles(target.Host)
+			if err != nil {
+				return err
+			}
+			for _, service := range services {
+				if d, err := files.FindDescriptorByName(protoreflect.FullName(service)); err == nil {
+					if sd, ok := d.(protoreflect.ServiceDescriptor); ok {
+						addMethods(sd)
+					}
+				}
+			}
+			return nil
+		}()
+	}
+
+	// methods can be both in the config and reflected
+	sort.Strings(methods)
+	unique := methods[:0]
+	for i, m := range methods {
+		if i == 0 || m != methods[i-1] {
+			unique = append(unique, m)
+		}
+	}
+	return unique, err
+}
+
+// ToggleGRPCMethods lists the callable gRPC methods, the selected method
+// is set in the URL
+func (a *App) ToggleGRPCMethods(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == GRPC_METHODS_VIEW {
+		a.closePopup(g, GRPC_METHODS_VIEW)
+		return nil
+	}
+
+	rawURL := a.substituteVariables(getViewValue(g, URL_VIEW))
+	headers := a.viewHeaders(g)
+	popup(g, "Loading gRPC services..")
+	go func() {
+		ctx := context.Background()
+		if timeout := a.config.General.Timeout.Duration; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		methods, err := a.listGRPCMethods(ctx, rawURL, headers)
+		g.UpdateAsync(func(g *gocui.Gui) error {
+			g.DeleteView(POPUP_VIEW)
+			if len(methods) == 0 {
+				if err == nil {
+					err = errors.New("No services found, use a grpc:// URL of a server with reflection or configure .proto files")
+				}
+				return a.OpenSaveResultView("gRPC error: "+err.Error(), g)
+			}
+			a.grpcMethodList = methods
+			list, viewErr := a.CreatePopupView(GRPC_METHODS_VIEW, 70, len(methods), g)
+			if viewErr != nil {
+				return viewErr
+			}
+			list.Title = VIEW_TITLES[GRPC_METHODS_VIEW]
+			if err != nil {
+				list.Title += " [!] " + err.Error()
+			}
+			current := ""
+			if u, err := url.Parse(rawURL); err == nil {
+				current = strings.TrimPrefix(u.Path, "/")
+			}
+			for i, m := range methods {
+				fmt.Fprintln(list, m)
+				if m == current {
+					list.SetCursor(0, i)
+				}
+			}
+			g.SetViewOnTop(GRPC_METHODS_VIEW)
+			g.SetCurrentView(GRPC_METHODS_VIEW)
+			return nil
+		})
+	}()
+	return nil
+}
+
+// selectGRPCMethod sets the path of the URL to the selected method. The
+// data view is filled with a template of the input message unless it
+// already holds a valid message, descriptors which are not known yet are
+// reflected in the background.
+func (a *App) selectGRPCMethod(g *gocui.Gui, v *gocui.View) error {
+	idx := viewCursorLine(g, GRPC_METHODS_VIEW)
+	if idx >= len(a.grpcMethodList) {
+		return nil
+	}
+	method := a.grpcMethodList[idx]
+	a.closePopup(g, GRPC_METHODS_VIEW)
+
+	rawURL, replaced := grpcMethodURL(getViewValue(g, URL_VIEW), method)
+	u, err := url.Parse(a.substituteVariables(rawURL))
+	if err != nil {
+		return nil
+	}
+	if !replaced {
+		// the scheme is set by a variable
+		u.Scheme = grpcScheme(u.Scheme)
+		u.Path = "/" + method
+		rawURL = u.String()
+	}
+	vurl, _ := g.View(URL_VIEW)
+	setViewTextAndCursor(vurl, rawURL)
+	vmethod, _ := g.View(REQUEST_METHOD_VIEW)
+	setViewTextAndCursor(vmethod, http.MethodPost)
+
+	headers := a.viewHeaders(g)
+	web := setGRPCHeaders(headers)
+	target := grpcTarget(u)
+	// the methods are usually reflected already by listing them
+	if call := a.cachedGRPCMethod(target, web, method); call != nil {
+		a.setGRPCMethodTemplate(g, call)
+		return nil
+	}
+	go func() {
+		ctx := context.Background()
+		if timeout := a.config.General.Timeout.Duration; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		call, err := a.grpcMethod(ctx, target, headers, web, method)
+		if err != nil {
+			return
+		}
+		g.UpdateAsync(func(g *gocui.Gui) error {
+			// another method may have been selected in the meantime
+			if getViewValue(g, URL_VIEW) == rawURL {
+				a.setGRPCMethodTemplate(g, call)
+			}
+			return nil
+		})
+	}()
+	return nil
+}
+
+func grpcScheme(scheme string) string {
+	switch scheme {
+	case "http", "grpc":
+		return "grpc"
+	}
+	return "grpcs"
+}
+
+// grpcMethodURL replaces the scheme and the path of the URL text, the
+// {{name}} placeholders of the host are kept. The URL is returned
+// unchanged if it does not start with a scheme.
+func grpcMethodURL(rawURL, method string) (string, bool) {
+	scheme, rest, found := strings.Cut(rawURL, "://")
+	if !found || strings.Contains(scheme, "{{") {
+		return rawURL, false
+	}
+	host, query := rest, ""
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		host = rest[:i]
+		if j := strings.Index(rest, "?"); j >= 0 {
+			query = rest[j:]
+		}
+	}
+	return grpcScheme(strings.ToLower(scheme)) + "://" + host + "/" + method + query, true
+}
+
+// setGRPCMethodTemplate fills the data view with a template of the input
+// message of the call unless it already holds a valid message
+func (a *App) setGRPCMethodTemplate(g *gocui.Gui, call *grpcCall) {
+	data := a.substituteVariables(getViewValue(g, REQUEST_DATA_VIEW))
+	if data != "" && (protojson.UnmarshalOptions{Resolver: call.types}).Unmarshal([]byte(data), dynamicpb.NewMessage(call.method.Input())) == nil {
+		return
+	}
+	vdata, _ := g.View(REQUEST_DATA_VIEW)
+	setViewTextAndCursor(vdata, grpcMethodTemplate(call))
+}
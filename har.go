@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asciimoo/wuzz/formatter"
+	"github.com/asciimoo/wuzz/request"
+)
+
+// HAR 1.2 format, see http://www.softwareishard.com/blog/har-12-spec/
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []harNameValue `json:"params,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+func durationToMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// millisToDuration converts HAR timings, -1 means not applicable
+func millisToDuration(ms float64) time.Duration {
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func exportHARSelected(a *App, _ Request) []byte {
+	if len(a.history) == 0 {
+		return exportHAR(a, nil)
+	}
+	return exportHAR(a, a.history[a.historyIndex:a.historyIndex+1])
+}
+
+func exportHARHistory(a *App, _ Request) []byte {
+	return exportHAR(a, a.history)
+}
+
+func exportHAR(a *App, requests []*Request) []byte {
+	har := harFile{harLog{
+		Version: "1.2",
+		Creator: harCreator{"wuzz", VERSION},
+		Entries: make([]harEntry, 0, len(requests)),
+	}}
+	for _, r := range requests {
+		har.Log.Entries = append(har.Log.Entries, a.toHAREntry(r))
+	}
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return []byte{}
+	}
+	return data
+}
+
+func (a *App) toHAREntry(r *Request) harEntry {
+	proto := r.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	u := a.substituteVariables(r.Url)
+	query := []harNameValue{}
+	if parsed, err := a.requestBuilder().URL(&request.Request{URL: r.Url, Params: r.GetParams}); err == nil {
+		u = parsed.String()
+		query = harValues(parsed.Query())
+	}
+
+	headers := []harNameValue{}
+	contentType := ""
+	for _, header := range strings.Split(a.substituteVariables(r.Headers), "\n") {
+		header_parts := strings.SplitN(header, ": ", 2)
+		if len(header_parts) != 2 {
+			continue
+		}
+		if http.CanonicalHeaderKey(header_parts[0]) == "Content-Type" {
+			contentType = header_parts[1]
+		}
+		headers = append(headers, harNameValue{header_parts[0], header_parts[1]})
+	}
+
+	request := harRequest{
+		Method:      r.Method,
+		URL:         u,
+		HTTPVersion: proto,
+		Cookies:     []harNameValue{},
+		Headers:     headers,
+		QueryString: query,
+		HeadersSize: -1,
+	}
+	if r.Data != "" {
+		data := a.substituteVariables(r.body())
+		request.PostData = &harPostData{MimeType: contentType, Text: data}
+		request.BodySize = len(data)
+	}
+
+	content := harContent{
+		Size:     len(r.RawResponseBody),
+		MimeType: r.ContentType,
+	}
+	if utf8.Valid(r.RawResponseBody) {
+		content.Text = string(r.RawResponseBody)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(r.RawResponseBody)
+		content.Encoding = "base64"
+	}
+
+	return harEntry{
+		StartedDateTime: r.Timestamp.Format(time.RFC3339Nano),
+		Time:            durationToMillis(r.Duration),
+		Request:         request,
+		Response: harResponse{
+			Status:      r.StatusCode,
+			StatusText:  http.StatusText(r.StatusCode),
+			HTTPVersion: proto,
+			Cookies:     []harNameValue{},
+			Headers:     harValues(r.RawResponseHeaders),
+			Content:     content,
+			RedirectURL: r.RawResponseHeaders.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(r.RawResponseBody),
+		},
+		Timings: timingsToHAR(r),
+	}
+}
+
+func timingsToHAR(r *Request) harTimings {
+	if r.Timing.TimeToFirstByte == 0 {
+		return harTimings{
+			Blocked: -1,
+			DNS:     -1,
+			Connect: -1,
+			SSL:     -1,
+			Wait:    durationToMillis(r.Duration),
+		}
+	}
+	ssl := -1.0
+	if r.Timing.TLSHandshake > 0 {
+		ssl = durationToMillis(r.Timing.TLSHandshake)
+	}
+	return harTimings{
+		Blocked: -1,
+		DNS:     durationToMillis(r.Timing.DNSLookup),
+		// the HAR connect time includes the TLS handshake
+		Connect: durationToMillis(r.Timing.TCPConnect + r.Timing.TLSHandshake),
+		SSL:     ssl,
+		Wait:    durationToMillis(r.Timing.ServerProcessing),
+		Receive: durationToMillis(r.Timing.ContentTransfer),
+	}
+}
+
+func harValues(values map[string][]string) []harNameValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]harNameValue, 0, len(values))
+	for _, name := range names {
+		for _, v := range values[name] {
+			ret = append(ret, harNameValue{name, v})
+		}
+	}
+	return ret
+}
+
+// LoadHAR appends the entries of a HAR file to the history
+func (a *App) LoadHAR(loadLocation string) error {
+	data, err := ioutil.ReadFile(loadLocation)
+	if err != nil {
+		return err
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return err
+	}
+	if len(har.Log.Entries) == 0 {
+		return errors.New("No entries found")
+	}
+	for _, e := range har.Log.Entries {
+		r, err := requestFromHAREntry(e)
+		if err != nil {
+			return err
+		}
+		r.Formatter = formatter.New(a.config, r.ContentType)
+		a.history = append(a.history, r)
+	}
+	a.historyIndex = len(a.history) - 1
+	return nil
+}
+
+func requestFromHAREntry(e harEntry) (*Request, error) {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{
+		Method:      e.Request.Method,
+		GetParams:   u.RawQuery,
+		StatusCode:  e.Response.Status,
+		Proto:       e.Response.HTTPVersion,
+		ContentType: e.Response.Content.MimeType,
+		Duration:    millisToDuration(e.Time),
+	}
+	if e.Timings.Wait >= 0 {
+		connect := e.Timings.Connect
+		if e.Timings.SSL > 0 {
+			connect -= e.Timings.SSL
+		}
+		r.Timing = RequestTiming{
+			DNSLookup:        millisToDuration(e.Timings.DNS),
+			TCPConnect:       millisToDuration(connect),
+			TLSHandshake:     millisToDuration(e.Timings.SSL),
+			ServerProcessing: millisToDuration(e.Timings.Wait),
+			ContentTransfer:  millisToDuration(e.Timings.Receive),
+		}
+		r.Timing.TimeToFirstByte = millisToDuration(e.Time - e.Timings.Receive)
+	}
+	u.RawQuery = ""
+	r.Url = u.String()
+	r.Timestamp, _ = time.Parse(time.RFC3339Nano, e.StartedDateTime)
+
+	headers := &strings.Builder{}
+	for _, h := range e.Request.Headers {
+		// skip HTTP/2 pseudo headers
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		fmt.Fprintf(headers, "%v: %v\n", h.Name, h.Value)
+	}
+	r.Headers = strings.TrimSpace(headers.String())
+
+	if e.Request.PostData != nil {
+		r.Data = e.Request.PostData.Text
+		if r.Data == "" && len(e.Request.PostData.Params) > 0 {
+			params := make([]string, 0, len(e.Request.PostData.Params))
+			for _, p := range e.Request.PostData.Params {
+				params = append(params, p.Name+"="+p.Value)
+			}
+			r.Data = strings.Join(params, "\n")
+		}
+	}
+
+	r.RawResponseHeaders = http.Header{}
+	for _, h := range e.Response.Headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		r.RawResponseHeaders.Add(h.Name, h.Value)
+	}
+	r.ResponseHeaders = formatResponseHeaders(r.Proto, r.StatusCode, r.RawResponseHeaders, nil)
+
+	r.RawResponseBody = []byte(e.Response.Content.Text)
+	if e.Response.Content.Encoding == "base64" {
+		r.RawResponseBody, err = base64.StdEncoding.DecodeString(e.Response.Content.Text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mitchellh/go-homedir"
+)
+
+const HISTORY_FILE_NAME = "history.json"
+
+// CREDENTIAL_HEADERS are the sent headers added by auth, signing and the
+// cookie jar which are redacted in the history store
+var CREDENTIAL_HEADERS = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Amz-Security-Token"}
+
+// historyStore writes the history in the background. The entries of
+// completed requests are encoded once.
+type historyStore struct {
+	sync.Mutex
+	entries map[*Request][]byte
+	// sequence numbers of the last scheduled and the last written save
+	scheduled, written uint64
+	writes             sync.WaitGroup
+	// err is the error of the last write
+	err error
+}
+
+func (a *App) historyLocation() string {
+	if a.config.General.HistoryFile != "" {
+		location, err := homedir.Expand(a.config.General.HistoryFile)
+		if err == nil {
+			return location
+		}
+		return a.config.General.HistoryFile
+	}
+	return filepath.Join(a.configDir, HISTORY_FILE_NAME)
+}
+
+// LoadHistory reads the persisted requests of the previous sessions
+func (a *App) LoadHistory() error {
+	if !a.config.General.PersistHistory {
+		return nil
+	}
+	data, err := ioutil.ReadFile(a.historyLocation())
+	if err != nil {
+		return err
+	}
+	var history []*Request
+	if err := json.Unmarshal(data, &history); err != nil {
+		return err
+	}
+	for _, r := range history {
+		r.Formatter = a.responseFormatter(r)
+	}
+	a.history = append(history, a.history...)
+	if len(a.history) > 0 {
+		a.historyIndex = len(a.history) - 1
+	}
+	return nil
+}
+
+// SaveHistory writes the history to disk in the background. Only the
+// latest HistoryLimit requests are kept and the oldest requests are
+// dropped until the store fits into HistorySizeLimit bytes. Write errors
+// are shown in the status line.
+func (a *App) SaveHistory(g *gocui.Gui) error {
+	if !a.config.General.PersistHistory {
+		return nil
+	}
+	history := a.history
+	if limit := a.config.General.HistoryLimit; limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	entries := make([][]byte, 0, len(history))
+	cache := make(map[*Request][]byte, len(history))
+	size := 2
+	for _, r := range history {
+		entry, found := a.historyStore.entries[r]
+		if !found {
+			var err error
+			if entry, err = json.Marshal(redactCredentials(r)); err != nil {
+				return err
+			}
+		}
+		// requests in flight and open websocket sessions still change
+		if r != a.activeRequest && (a.webSocket == nil || a.webSocket.request != r) {
+			cache[r] = entry
+		}
+		entries = append(entries, entry)
+		size += len(entry) + 1
+	}
+	a.historyStore.entries = cache
+	if sizeLimit := a.config.General.HistorySizeLimit; sizeLimit > 0 {
+		for len(entries) > 0 && size > sizeLimit {
+			size -= len(entries[0]) + 1
+			entries = entries[1:]
+		}
+	}
+
+	data := bytes.NewBufferString("[")
+	data.Write(bytes.Join(entries, []byte(",")))
+	data.WriteString("]")
+
+	location := a.historyLocation()
+	s := &a.historyStore
+	s.Lock()
+	s.scheduled++
+	seq := s.scheduled
+	s.Unlock()
+	s.writes.Add(1)
+	go func() {
+		defer s.writes.Done()
+		s.Lock()
+		defer s.Unlock()
+		// a later save was written already
+		if seq < s.written {
+			return
+		}
+		s.written = seq
+		err := writeHistoryFile(location, data.Bytes())
+		if err == nil && s.err == nil {
+			return
+		}
+		s.err = err
+		// the status line may be refreshed after the main loop ended
+		g.Update(func(g *gocui.Gui) error {
+			refreshStatusLine(a, g)
+			return nil
+		})
+	}()
+	return nil
+}
+
+// historyError returns the error of the last history write
+func (a *App) historyError() error {
+	a.historyStore.Lock()
+	defer a.historyStore.Unlock()
+	return a.historyStore.err
+}
+
+// redactCredentials returns a copy of r without the values of the sent
+// credential headers
+func redactCredentials(r *Request) *Request {
+	redacted := *r
+	redacted.SentHeaders = r.SentHeaders.Clone()
+	for _, name := range CREDENTIAL_HEADERS {
+		if _, found := redacted.SentHeaders[name]; found {
+			redacted.SentHeaders[name] = []string{"[redacted]"}
+		}
+	}
+	return &redacted
+}
+
+// writeHistoryFile writes to a temporary file first to never leave a
+// truncated store behind
+func writeHistoryFile(location string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
+		return err
+	}
+	tmpLocation := location + ".tmp"
+	if err := ioutil.WriteFile(tmpLocation, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpLocation, location)
+}
+
+// WaitHistory waits until the history is written
+func (a *App) WaitHistory() {
+	a.historyStore.writes.Wait()
+}
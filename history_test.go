@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedactCredentials(t *testing.T) {
+	r := &Request{SentHeaders: http.Header{
+		"Authorization": {"Bearer token"},
+		"Cookie":        {"session=1"},
+		"Accept":        {"*/*"},
+	}}
+	redacted := redactCredentials(r)
+	if redacted.SentHeaders.Get("Authorization") != "[redacted]" || redacted.SentHeaders.Get("Cookie") != "[redacted]" {
+		t.Error("Expected redacted credentials, got", redacted.SentHeaders)
+	}
+	if redacted.SentHeaders.Get("Accept") != "*/*" {
+		t.Error("Unexpected Accept header", redacted.SentHeaders.Get("Accept"))
+	}
+	if _, found := redacted.SentHeaders["Proxy-Authorization"]; found {
+		t.Error("Expected no Proxy-Authorization header")
+	}
+	if r.SentHeaders.Get("Authorization") != "Bearer token" {
+		t.Error("Expected the request unchanged, got", r.SentHeaders)
+	}
+	if redactCredentials(&Request{}).SentHeaders != nil {
+		t.Error("Expected no sent headers")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	HTTP_VERSION_1_1 = "1.1"
+	// HTTP/2 is negotiated with ALPN, cleartext requests use HTTP/1.1
+	HTTP_VERSION_2 = "2"
+	// HTTP/2 without negotiation, cleartext requests use h2c
+	HTTP_VERSION_2_PRIOR_KNOWLEDGE = "2-prior-knowledge"
+)
+
+// WEBSOCKET_CLIENT sends the websocket handshakes, which require HTTP/1.1
+var WEBSOCKET_CLIENT = &http.Client{}
+
+// httpProtocols returns the protocols of TRANSPORT for the httpVersion
+// config value
+func httpProtocols(version string) (*http.Protocols, error) {
+	protocols := new(http.Protocols)
+	switch version {
+	case HTTP_VERSION_1_1:
+		protocols.SetHTTP1(true)
+	case HTTP_VERSION_2, "":
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	case HTTP_VERSION_2_PRIOR_KNOWLEDGE:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("Unknown HTTP version: %v (values: %v, %v, %v)", version, HTTP_VERSION_1_1, HTTP_VERSION_2, HTTP_VERSION_2_PRIOR_KNOWLEDGE)
+	}
+	return protocols, nil
+}
+
+// alpnProtocols returns the protocols offered in the TLS handshake. They
+// are set explicitly as the transport adds h2 only once, a reapplied TLS
+// config would disable HTTP/2.
+func alpnProtocols(protocols *http.Protocols) []string {
+	switch {
+	case protocols.HTTP2() && protocols.HTTP1():
+		return []string{"h2", "http/1.1"}
+	case protocols.HTTP2():
+		return []string{"h2"}
+	}
+	return nil
+}
+
+// initWebSocketTransport derives the HTTP/1.1 only transport of websocket
+// handshakes from TRANSPORT
+func initWebSocketTransport() {
+	transport := TRANSPORT.Clone()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetHTTP1(true)
+	if transport.TLSClientConfig != nil {
+		transport.TLSClientConfig.NextProtos = nil
+	}
+	WEBSOCKET_CLIENT.Transport = transport
+	WEBSOCKET_CLIENT.CheckRedirect = CLIENT.CheckRedirect
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// RedirectHop is a redirect response which was followed
+type RedirectHop struct {
+	Method     string
+	URL        string
+	Proto      string
+	StatusCode int
+	Headers    http.Header
+}
+
+// redirectChain records the redirects followed by a request
+type redirectChain struct {
+	hops         []RedirectHop
+	limitReached bool
+}
+
+type redirectChainKey struct{}
+
+func withRedirectChain(ctx context.Context) (context.Context, *redirectChain) {
+	chain := &redirectChain{}
+	return context.WithValue(ctx, redirectChainKey{}, chain), chain
+}
+
+// checkRedirect is the CheckRedirect function of CLIENT. The last response
+// is returned if redirects are disabled or the maximum is reached.
+func (a *App) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !a.config.General.FollowRedirects {
+		return http.ErrUseLastResponse
+	}
+	chain, _ := req.Context().Value(redirectChainKey{}).(*redirectChain)
+	if max := a.config.General.MaxRedirects; max >= 0 && len(via) > max {
+		if chain != nil {
+			chain.limitReached = true
+		}
+		return http.ErrUseLastResponse
+	}
+	if chain != nil && req.Response != nil {
+		previous := via[len(via)-1]
+		chain.hops = append(chain.hops, RedirectHop{
+			Method:     previous.Method,
+			URL:        previous.URL.String(),
+			Proto:      req.Response.Proto,
+			StatusCode: req.Response.StatusCode,
+			Headers:    req.Response.Header.Clone(),
+		})
+	}
+	// the client repeats the method and the body on 307 and 308
+	// redirects, like on 301-303 redirects GET is used if disabled
+	if req.Response != nil && !a.config.General.PreserveRedirectMethod && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		switch req.Response.StatusCode {
+		case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			req.Method = http.MethodGet
+			req.Body = nil
+			req.GetBody = nil
+			req.ContentLength = 0
+			req.Header.Del("Content-Type")
+		}
+	}
+	return nil
+}
+
+// redirectCount returns the number of followed redirects of a request
+func redirectCount(r *Request) string {
+	if len(r.Redirects) == 0 && !r.RedirectLimitReached {
+		return ""
+	}
+	count := strconv.Itoa(len(r.Redirects))
+	if r.RedirectLimitReached {
+		count += " (limit reached)"
+	}
+	return count
+}
+
+// ToggleRedirects shows the redirects followed by the selected request
+func (a *App) ToggleRedirects(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == REDIRECTS_VIEW {
+		a.closePopup(g, REDIRECTS_VIEW)
+		return nil
+	}
+
+	maxX, maxY := g.Size()
+	chain, err := a.CreatePopupView(REDIRECTS_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	chain.Title = VIEW_TITLES[REDIRECTS_VIEW]
+	chain.Highlight = false
+	chain.Wrap = true
+	g.SetViewOnTop(REDIRECTS_VIEW)
+	g.SetCurrentView(REDIRECTS_VIEW)
+
+	if len(a.history) == 0 {
+		setViewTextAndCursor(chain, "[!] No request sent yet")
+		return nil
+	}
+	r := a.history[a.historyIndex]
+	if len(r.Redirects) == 0 && !r.RedirectLimitReached {
+		setViewTextAndCursor(chain, "[!] No redirects followed")
+		return nil
+	}
+	for i, hop := range r.Redirects {
+		fmt.Fprintf(chain, "\x1b[0;32m#%d %v %v\x1b[0;0m\n", i+1, hop.Method, hop.URL)
+		fmt.Fprintf(chain, "\x1b[0;33m%v %v %v\x1b[0;0m\n", hop.Proto, hop.StatusCode, http.StatusText(hop.StatusCode))
+		writeSortedHeaders(chain, hop.Headers)
+		fmt.Fprintln(chain)
+	}
+	fmt.Fprintf(chain, "\x1b[0;32m#%d %v %v\x1b[0;0m\n", len(r.Redirects)+1, r.SentMethod, r.SentURL)
+	fmt.Fprintf(chain, "\x1b[0;33m%v %v %v\x1b[0;0m\n", r.Proto, r.StatusCode, http.StatusText(r.StatusCode))
+	if r.RedirectLimitReached {
+		fmt.Fprintf(chain, "\x1b[0;31m[!] Stopped after %d redirects\x1b[0;0m\n", len(r.Redirects))
+	}
+	return nil
+}
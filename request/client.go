@@ -0,0 +1,116 @@
+package request
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Doer sends HTTP requests, it is implemented by *http.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client builds and sends requests
+type Client struct {
+	Builder
+	// HTTPClient sends the requests, http.DefaultClient is used if nil
+	HTTPClient Doer
+}
+
+// Response is a completely received response
+type Response struct {
+	Proto      string
+	StatusCode int
+	Header     http.Header
+	Trailer    http.Header
+	// Body is the uncompressed response body
+	Body []byte
+	// Duration is the time until the whole body was received
+	Duration time.Duration
+	// Request is the last sent request, e.g. after redirects
+	Request *http.Request
+	TLS     *tls.ConnectionState
+}
+
+// Do sends an HTTP request, the body of the response is uncompressed
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	var client Doer = http.DefaultClient
+	if c.HTTPClient != nil {
+		client = c.HTTPClient
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	Uncompress(response)
+	return response, nil
+}
+
+// Send builds and sends a request and reads the whole response
+func (c *Client) Send(ctx context.Context, r *Request) (*Response, error) {
+	req, err := c.Build(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	response, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	result := &Response{
+		Proto:      response.Proto,
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Request:    response.Request,
+		TLS:        response.TLS,
+	}
+	if result.Body, err = io.ReadAll(response.Body); err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(start)
+	// the trailers are set after the body is read
+	result.Trailer = response.Trailer
+	return result, nil
+}
+
+// gzipBody uncompresses a response body while it is read, empty bodies
+// are not compressed
+type gzipBody struct {
+	body   io.ReadCloser
+	reader *gzip.Reader
+	err    error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = gzip.NewReader(b.body)
+		if b.err != nil && b.err != io.EOF {
+			b.err = fmt.Errorf("Cannot uncompress response: %v", b.err)
+		}
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	if b.reader != nil {
+		b.reader.Close()
+	}
+	return b.body.Close()
+}
+
+// Uncompress replaces the body of a gzip encoded response with the
+// uncompressed body
+func Uncompress(response *http.Response) {
+	if response.Header.Get("Content-Encoding") == "gzip" {
+		response.Body = &gzipBody{body: response.Body}
+	}
+}
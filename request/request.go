@@ -0,0 +1,168 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// Request is an HTTP request in the text form of the request views
+type Request struct {
+	Method string
+	URL    string
+	// Params are the GET parameters added to the URL, one name=value
+	// pair per line or separated by &
+	Params string
+	// Headers has one "Name: value" header per line
+	Headers string
+	// Data is the body of POST, PUT and PATCH requests, it is encoded
+	// as a form if the Content-Type header is a form content type. Form
+	// values starting with @ upload files in multipart forms.
+	Data string
+	// Body is sent instead of Data if it is not nil
+	Body []byte
+}
+
+// Builder creates HTTP requests from Requests
+type Builder struct {
+	// Substitute replaces the variables of the request fields
+	Substitute func(string) string
+}
+
+func (b Builder) substitute(s string) string {
+	if b.Substitute == nil {
+		return s
+	}
+	return b.Substitute(s)
+}
+
+// HasBody reports whether the data of requests with the method is sent
+func HasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// URL returns the URL of a request with its GET parameters
+func (b Builder) URL(r *Request) (*url.URL, error) {
+	u, err := url.Parse(b.substitute(r.URL))
+	if err != nil {
+		return nil, fmt.Errorf("URL parse error: %v", err)
+	}
+	q, err := url.ParseQuery(strings.Replace(b.substitute(r.Params), "\n", "&", -1))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid GET parameters: %v", err)
+	}
+	originalQuery := u.Query()
+	for k, v := range q {
+		for _, qp := range v {
+			originalQuery.Add(k, qp)
+		}
+	}
+	u.RawQuery = originalQuery.Encode()
+	return u, nil
+}
+
+// Header parses the headers of a request. No User-Agent header is sent
+// unless it is set.
+func (b Builder) Header(r *Request) (http.Header, error) {
+	headers := http.Header{}
+	headers.Set("User-Agent", "")
+	for _, header := range strings.Split(b.substitute(r.Headers), "\n") {
+		if header != "" {
+			header_parts := strings.SplitN(header, ": ", 2)
+			if len(header_parts) != 2 {
+				return nil, fmt.Errorf("Invalid header: %v", header)
+			}
+			headers.Set(header_parts[0], header_parts[1])
+		}
+	}
+	return headers, nil
+}
+
+// Body returns the body of a request or nil if the method has no body.
+// The boundary of multipart forms is added to the Content-Type header.
+func (b Builder) Body(r *Request, headers http.Header) (io.Reader, error) {
+	if r.Body != nil {
+		return bytes.NewReader(r.Body), nil
+	}
+	if !HasBody(r.Method) {
+		return nil, nil
+	}
+	bodyStr := b.substitute(r.Data)
+	if headers.Get("Content-Type") != "multipart/form-data" {
+		if headers.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			bodyStr = strings.Replace(bodyStr, "\n", "&", -1)
+		}
+		return bytes.NewBufferString(bodyStr), nil
+	}
+	var bodyBytes bytes.Buffer
+	multiWriter := multipart.NewWriter(&bodyBytes)
+	postData, err := url.ParseQuery(strings.Replace(bodyStr, "\n", "&", -1))
+	if err != nil {
+		return nil, err
+	}
+	for postKey, postValues := range postData {
+		for i := range postValues {
+			if len([]rune(postValues[i])) > 0 && postValues[i][0] == '@' {
+				file, err := os.Open(postValues[i][1:])
+				if err != nil {
+					return nil, err
+				}
+				fw, err := multiWriter.CreateFormFile(postKey, path.Base(postValues[i][1:]))
+				if err == nil {
+					_, err = io.Copy(fw, file)
+				}
+				file.Close()
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				fw, err := multiWriter.CreateFormField(postKey)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := fw.Write([]byte(postValues[i])); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	// the closing boundary is written by Close
+	if err := multiWriter.Close(); err != nil {
+		return nil, err
+	}
+	headers.Set("Content-Type", multiWriter.FormDataContentType())
+	return bytes.NewReader(bodyBytes.Bytes()), nil
+}
+
+// Build creates the HTTP request of a request
+func (b Builder) Build(ctx context.Context, r *Request) (*http.Request, error) {
+	u, err := b.URL(r)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := b.Header(r)
+	if err != nil {
+		return nil, err
+	}
+	body, err := b.Body(r, headers)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("Request error: %v", err)
+	}
+	req.Header = headers
+	// set the `Host` header
+	if headers.Get("Host") != "" {
+		req.Host = headers.Get("Host")
+	}
+	return req, nil
+}
@@ -0,0 +1,227 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoServer responds with the method, URL, headers and body of requests
+func echoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-URL", r.URL.String())
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-User-Agent", r.UserAgent())
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestURL(t *testing.T) {
+	b := Builder{Substitute: func(s string) string {
+		return strings.Replace(s, "{{host}}", "example.com", -1)
+	}}
+	u, err := b.URL(&Request{URL: "https://{{host}}/path?a=1", Params: "b=2\nc=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != "https://example.com/path?a=1&b=2&c=3" {
+		t.Error("Unexpected URL", u.String())
+	}
+
+	if _, err := b.URL(&Request{URL: "https://example.com/", Params: "a=%zz"}); err == nil {
+		t.Error("Expected invalid GET parameters to fail")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h, err := Builder{}.Header(&Request{Headers: "Accept: text/plain\nX-Empty: \n\nX-Value: a: b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("Accept") != "text/plain" || h.Get("X-Value") != "a: b" {
+		t.Error("Unexpected headers", h)
+	}
+	if _, err := (Builder{}).Header(&Request{Headers: "Accept text/plain"}); err == nil || err.Error() != "Invalid header: Accept text/plain" {
+		t.Error("Expected invalid header error, got", err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	server := echoServer(t)
+	client := &Client{}
+
+	response, err := client.Send(context.Background(), &Request{
+		Method:  http.MethodGet,
+		URL:     server.URL + "/get",
+		Params:  "q=1",
+		Headers: "Host: example.com",
+		Data:    "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || response.Proto != "HTTP/1.1" {
+		t.Error("Unexpected status", response.StatusCode, response.Proto)
+	}
+	if response.Header.Get("X-URL") != "/get?q=1" || response.Header.Get("X-Host") != "example.com" {
+		t.Error("Unexpected request", response.Header)
+	}
+	if response.Header.Get("X-User-Agent") != "" {
+		t.Error("Expected no User-Agent, got", response.Header.Get("X-User-Agent"))
+	}
+	if len(response.Body) != 0 {
+		t.Error("Expected the data of a GET request to be ignored, got", string(response.Body))
+	}
+	if response.Request.Method != http.MethodGet {
+		t.Error("Expected the sent request in the response")
+	}
+
+	response, err = client.Send(context.Background(), &Request{
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Headers: "Content-Type: application/x-www-form-urlencoded",
+		Data:    "a=1\nb=2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != "a=1&b=2" {
+		t.Error("Expected form data a=1&b=2, got", string(response.Body))
+	}
+
+	response, err = client.Send(context.Background(), &Request{
+		Method:  http.MethodPut,
+		URL:     server.URL,
+		Headers: "Content-Type: application/json",
+		Data:    "{\n\"a\": 1\n}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != "{\n\"a\": 1\n}" {
+		t.Error("Expected the data unchanged, got", string(response.Body))
+	}
+
+	response, err = client.Send(context.Background(), &Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Data:   "ignored",
+		Body:   []byte("body"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != "body" {
+		t.Error("Expected Body to replace Data, got", string(response.Body))
+	}
+
+	if _, err := client.Send(context.Background(), &Request{Method: http.MethodGet, URL: server.URL, Headers: "invalid"}); err == nil {
+		t.Error("Expected an invalid header to fail")
+	}
+}
+
+func TestSendMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1024 * 1024); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+		io.WriteString(w, r.FormValue("name")+" "+header.Filename+" "+string(content))
+	}))
+	defer server.Close()
+
+	upload := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(upload, []byte("file content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	response, err := (&Client{}).Send(context.Background(), &Request{
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Headers: "Content-Type: multipart/form-data",
+		Data:    "name=wuzz\nfile=@" + upload,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(response.Body) != "wuzz upload.txt file content" {
+		t.Error("Unexpected multipart response", response.StatusCode, string(response.Body))
+	}
+
+	_, err = (&Client{}).Send(context.Background(), &Request{
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Headers: "Content-Type: multipart/form-data",
+		Data:    "file=@" + filepath.Join(t.TempDir(), "missing"),
+	})
+	if err == nil {
+		t.Error("Expected a missing upload to fail")
+	}
+}
+
+func TestSendGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.Method == http.MethodHead {
+			return
+		}
+		var body bytes.Buffer
+		writer := gzip.NewWriter(&body)
+		writer.Write([]byte("uncompressed"))
+		writer.Close()
+		w.Write(body.Bytes())
+	}))
+	defer server.Close()
+
+	client := &Client{}
+	response, err := client.Send(context.Background(), &Request{
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Headers: "Accept-Encoding: gzip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != "uncompressed" {
+		t.Error("Expected an uncompressed body, got", string(response.Body))
+	}
+
+	response, err = client.Send(context.Background(), &Request{
+		Method:  http.MethodHead,
+		URL:     server.URL,
+		Headers: "Accept-Encoding: gzip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Body) != 0 {
+		t.Error("Expected an empty body, got", string(response.Body))
+	}
+}
+
+func TestSendCancel(t *testing.T) {
+	server := echoServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&Client{}).Send(ctx, &Request{Method: http.MethodGet, URL: server.URL}); err == nil {
+		t.Error("Expected a cancelled request to fail")
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/asciimoo/wuzz/config"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	SIGNING_AWS_SIGV4 = "aws-sigv4"
+	SIGNING_HMAC      = "hmac"
+
+	AWS_SIGV4_ALGORITHM = "AWS4-HMAC-SHA256"
+	AWS_TIME_FORMAT     = "20060102T150405Z"
+
+	// pseudo header of HMAC signatures signing the method and the path
+	HMAC_REQUEST_TARGET = "(request-target)"
+
+	HMAC_DEFAULT_FORMAT = `keyId="{{.KeyID}}",algorithm="{{.Algorithm}}",headers="{{.Headers}}",signature="{{.Signature}}"`
+)
+
+var HMAC_DEFAULT_HEADERS = []string{HMAC_REQUEST_TARGET, "host", "date"}
+
+var HMAC_ALGORITHMS = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// requestSigner signs requests after the auth headers are set, it runs
+// again if a request is sent again
+type requestSigner struct {
+	name string
+	config.Signing
+	// HMAC signature header template
+	formatTemplate *template.Template
+	// AWS credentials
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// hmacSignature holds the values of the HMAC signature header template
+type hmacSignature struct {
+	KeyID     string
+	Algorithm string
+	Headers   string
+	Signature string
+}
+
+// requestSigner returns the named signing of the config, nil if name is
+// empty
+func (a *App) requestSigner(name string) (*requestSigner, error) {
+	if name == "" {
+		return nil, nil
+	}
+	signing, found := a.config.Signing[name]
+	if !found {
+		return nil, fmt.Errorf("Unknown signing: %v", name)
+	}
+	for _, value := range []*string{&signing.Service, &signing.Region, &signing.Profile, &signing.Key, &signing.KeyID} {
+		*value = a.substituteVariables(*value)
+	}
+	s := &requestSigner{name: name, Signing: signing}
+	switch strings.ToLower(signing.Type) {
+	case SIGNING_AWS_SIGV4:
+		credentials, err := loadAWSCredentials(signing.Profile)
+		if err != nil {
+			return nil, err
+		}
+		s.accessKeyID = credentials.accessKeyID
+		s.secretAccessKey = credentials.secretAccessKey
+		s.sessionToken = credentials.sessionToken
+		if s.Region == "" {
+			s.Region = os.Getenv("AWS_REGION")
+		}
+		if s.Region == "" {
+			s.Region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+	case SIGNING_HMAC:
+		if signing.Key == "" {
+			return nil, fmt.Errorf("No HMAC key specified for %v", name)
+		}
+		if s.Algorithm == "" {
+			s.Algorithm = "sha256"
+		}
+		s.Algorithm = strings.ToLower(s.Algorithm)
+		if _, found := HMAC_ALGORITHMS[s.Algorithm]; !found {
+			return nil, fmt.Errorf("Unknown HMAC algorithm: %v (values: sha1, sha256, sha512)", signing.Algorithm)
+		}
+		switch s.Encoding {
+		case "":
+			s.Encoding = "base64"
+		case "base64", "hex":
+		default:
+			return nil, fmt.Errorf("Unknown HMAC signature encoding: %v (values: base64, hex)", s.Encoding)
+		}
+		if len(s.Headers) == 0 {
+			s.Headers = HMAC_DEFAULT_HEADERS
+		}
+		if s.Header == "" && s.QueryParam == "" {
+			s.Header = "Signature"
+		}
+		format := s.Format
+		if format == "" {
+			format = HMAC_DEFAULT_FORMAT
+		}
+		var err error
+		if s.formatTemplate, err = template.New(name).Parse(format); err != nil {
+			return nil, fmt.Errorf("Invalid HMAC signature format: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown signing type of %v: %v (values: %v, %v)", name, signing.Type, SIGNING_AWS_SIGV4, SIGNING_HMAC)
+	}
+	return s, nil
+}
+
+// loadAWSCredentials reads the credentials of the environment, or of a
+// profile of the shared credentials file. A configured profile takes
+// precedence over the environment.
+func loadAWSCredentials(profile string) (*awsCredentials, error) {
+	if profile == "" && os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return &awsCredentials{
+			accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	file := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if file == "" {
+		file = "~/.aws/credentials"
+	}
+	file, err := homedir.Expand(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("No AWS credentials found: %v", err)
+	}
+	defer f.Close()
+
+	credentials := &awsCredentials{}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || section != profile {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			credentials.accessKeyID = value
+		case "aws_secret_access_key":
+			credentials.secretAccessKey = value
+		case "aws_session_token":
+			credentials.sessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if credentials.accessKeyID == "" || credentials.secretAccessKey == "" {
+		return nil, fmt.Errorf("No AWS credentials found for profile %v in %v", profile, file)
+	}
+	return credentials, nil
+}
+
+// sign adds the signature headers to a request
+func (s *requestSigner) sign(req *http.Request) error {
+	var body []byte
+	if req.GetBody != nil {
+		var err error
+		if body, err = readRequestBody(req); err != nil {
+			return err
+		}
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return errors.New("Cannot sign a streamed request body")
+	}
+	if strings.ToLower(s.Type) == SIGNING_AWS_SIGV4 {
+		return s.signAWS(req, body, time.Now().UTC())
+	}
+	return s.signHMAC(req, body, time.Now().UTC())
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	io.WriteString(mac, data)
+	return mac.Sum(nil)
+}
+
+// awsURIEncode escapes every byte except the unreserved characters
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsScope returns the service and region of the signing, they default to
+// the ones of service.region.amazonaws.com hosts
+func (s *requestSigner) awsScope(host string) (service, region string, err error) {
+	service, region = s.Service, s.Region
+	hostname := strings.Split(host, ":")[0]
+	if labels := strings.Split(hostname, "."); len(labels) >= 4 && strings.HasSuffix(hostname, ".amazonaws.com") {
+		if service == "" {
+			service = labels[len(labels)-4]
+		}
+		if region == "" {
+			region = labels[len(labels)-3]
+		}
+	}
+	if service == "" {
+		return "", "", fmt.Errorf("No AWS service specified for %v", s.name)
+	}
+	if region == "" {
+		return "", "", fmt.Errorf("No AWS region specified for %v", s.name)
+	}
+	return service, region, nil
+}
+
+// signAWS signs the host, Content-Type and X-Amz-* headers with AWS
+// Signature Version 4
+func (s *requestSigner) signAWS(req *http.Request, body []byte, now time.Time) error {
+	host := requestHost(req)
+	service, region, err := s.awsScope(host)
+	if err != nil {
+		return err
+	}
+	amzDate := now.Format(AWS_TIME_FORMAT)
+	payloadHash := hexSHA256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	// S3 paths are encoded once, the paths of other services twice
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalURI := awsURIEncode(path, false)
+	if service != "s3" {
+		canonicalURI = awsURIEncode(canonicalURI, false)
+	}
+
+	// the parameters are sorted by the encoded name, then by value
+	query := req.URL.Query()
+	pairs := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{awsURIEncode(key, true), awsURIEncode(value, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	params := make([]string, len(pairs))
+	for i, pair := range pairs {
+		params[i] = pair[0] + "=" + pair[1]
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.Join(strings.Fields(v), " ")
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	signedHeaders := make([]string, 0, len(headers))
+	for name := range headers {
+		signedHeaders = append(signedHeaders, name)
+	}
+	sort.Strings(signedHeaders)
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		fmt.Fprintf(&canonicalHeaders, "%v:%v\n", name, headers[name])
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{AWS_SIGV4_ALGORITHM, amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + s.secretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%v Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		AWS_SIGV4_ALGORITHM, s.accessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+	return nil
+}
+
+// signHMAC signs the configured headers, one "name: value" line per
+// header. Missing Date and Digest headers are added.
+func (s *requestSigner) signHMAC(req *http.Request, body []byte, now time.Time) error {
+	lines := make([]string, len(s.Headers))
+	names := make([]string, len(s.Headers))
+	for i, name := range s.Headers {
+		name = strings.ToLower(name)
+		names[i] = name
+		var value string
+		switch name {
+		case HMAC_REQUEST_TARGET:
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = requestHost(req)
+		case "date":
+			if req.Header.Get("Date") == "" {
+				req.Header.Set("Date", now.Format(http.TimeFormat))
+			}
+			value = req.Header.Get("Date")
+		case "digest":
+			if req.Header.Get("Digest") == "" {
+				sum := sha256.Sum256(body)
+				req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+			}
+			value = req.Header.Get("Digest")
+		default:
+			values := req.Header.Values(name)
+			if len(values) == 0 {
+				return fmt.Errorf("Cannot sign missing header: %v", name)
+			}
+			value = strings.Join(values, ", ")
+		}
+		lines[i] = name + ": " + value
+	}
+
+	mac := hmac.New(HMAC_ALGORITHMS[s.Algorithm], []byte(s.Key))
+	io.WriteString(mac, strings.Join(lines, "\n"))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if s.Encoding == "hex" {
+		signature = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var value strings.Builder
+	err := s.formatTemplate.Execute(&value, hmacSignature{
+		KeyID:     s.KeyID,
+		Algorithm: "hmac-" + s.Algorithm,
+		Headers:   strings.Join(names, " "),
+		Signature: signature,
+	})
+	if err != nil {
+		return err
+	}
+	if s.QueryParam != "" {
+		query := req.URL.Query()
+		query.Set(s.QueryParam, value.String())
+		req.URL.RawQuery = query.Encode()
+	}
+	if s.Header != "" {
+		req.Header.Set(s.Header, value.String())
+	}
+	return nil
+}
+
+func (a *App) signingNames() []string {
+	names := make([]string, 0, len(a.config.Signing))
+	for name := range a.config.Signing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToggleSigning lists the configured signings, the selected signing is
+// used by the following requests
+func (a *App) ToggleSigning(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == SIGNING_VIEW {
+		a.closePopup(g, SIGNING_VIEW)
+		return nil
+	}
+	names := a.signingNames()
+	if len(names) == 0 {
+		return a.OpenSaveResultView("No signing configured, add [signing.NAME] sections to the config", g)
+	}
+	return a.openSelectionList(g, SIGNING_VIEW, names, a.config.General.Signing, func(name string) string {
+		return strings.ToLower(a.config.Signing[name].Type)
+	})
+}
+
+func (a *App) selectSigning(g *gocui.Gui, v *gocui.View) error {
+	idx := viewCursorLine(g, SIGNING_VIEW)
+	if idx >= len(a.selectionList) {
+		return nil
+	}
+	a.config.General.Signing = a.selectionList[idx]
+	a.closePopup(g, SIGNING_VIEW)
+	refreshStatusLine(a, g)
+	return nil
+}
+
+// ToggleSentRequest shows the request line and the headers sent by the
+// selected request, including the auth and signature headers
+func (a *App) ToggleSentRequest(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == SENT_REQUEST_VIEW {
+		a.closePopup(g, SENT_REQUEST_VIEW)
+		return nil
+	}
+
+	maxX, maxY := g.Size()
+	sent, err := a.CreatePopupView(SENT_REQUEST_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	sent.Title = VIEW_TITLES[SENT_REQUEST_VIEW]
+	sent.Highlight = false
+	sent.Wrap = true
+	g.SetViewOnTop(SENT_REQUEST_VIEW)
+	g.SetCurrentView(SENT_REQUEST_VIEW)
+
+	if len(a.history) == 0 || a.history[a.historyIndex].SentHeaders == nil {
+		setViewTextAndCursor(sent, "[!] No request sent yet")
+		return nil
+	}
+	r := a.history[a.historyIndex]
+	fmt.Fprintf(sent, "\x1b[0;32m%v %v\x1b[0;0m\n", r.SentMethod, r.SentURL)
+	writeSortedHeaders(sent, r.SentHeaders)
+	return nil
+}
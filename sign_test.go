@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/asciimoo/wuzz/config"
+)
+
+// signing time and credentials of the AWS SigV4 test suite
+var awsTestTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func awsTestSigner() *requestSigner {
+	return &requestSigner{
+		name:            "test",
+		Signing:         config.Signing{Type: SIGNING_AWS_SIGV4, Service: "service", Region: "us-east-1"},
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+}
+
+func TestSignAWS(t *testing.T) {
+	tests := []struct {
+		url       string
+		signature string
+	}{
+		// get-vanilla
+		{"https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		// get-vanilla-query-order-key-case
+		{"https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		// parameters are sorted by name before value
+		{"https://example.amazonaws.com/?a1=1&a.b=1&a=3&a-b=1&a=2", "07974fffa0af2fdec532e1bda3028fc63a4db2991ae013d0792fff94471aa75d"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := awsTestSigner().signAWS(req, nil, awsTestTime); err != nil {
+			t.Fatal(err)
+		}
+		expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + test.signature
+		if req.Header.Get("Authorization") != expected {
+			t.Errorf("Unexpected signature of %v: %v", test.url, req.Header.Get("Authorization"))
+		}
+		if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+			t.Error("Unexpected X-Amz-Date", req.Header.Get("X-Amz-Date"))
+		}
+	}
+}
+
+func TestSignHMAC(t *testing.T) {
+	s := &requestSigner{
+		name: "test",
+		Signing: config.Signing{
+			Type:      SIGNING_HMAC,
+			Key:       "secret",
+			KeyID:     "key",
+			Algorithm: "sha256",
+			Encoding:  "base64",
+			Headers:   HMAC_DEFAULT_HEADERS,
+			Header:    "Signature",
+		},
+		formatTemplate: template.Must(template.New("test").Parse(HMAC_DEFAULT_FORMAT)),
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/foo?a=1", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.signHMAC(req, []byte("body"), awsTestTime); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Date") != "Sun, 30 Aug 2015 12:36:00 GMT" {
+		t.Error("Unexpected Date", req.Header.Get("Date"))
+	}
+	expected := `keyId="key",algorithm="hmac-sha256",headers="(request-target) host date",signature="L/fGaCTMB3EkfIydz1jdroGDtZfibtDTaks6RX6YAzA="`
+	if req.Header.Get("Signature") != expected {
+		t.Error("Unexpected signature", req.Header.Get("Signature"))
+	}
+}
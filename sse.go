@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/asciimoo/wuzz/formatter"
+	"github.com/asciimoo/wuzz/request"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// appendEvents parses the new events of an event stream and renders them
+func (a *App) appendEvents(g *gocui.Gui, r *Request, sse *formatter.SSEFormatter) {
+	events := sse.Update(r.RawResponseBody)
+	r.Events = sse.Events
+	if len(events) == 0 || !a.isDisplayed(r) {
+		return
+	}
+	if getViewValue(g, SEARCH_VIEW) != "" {
+		a.printBody(g)
+		return
+	}
+	vrb, _ := g.View(RESPONSE_BODY_VIEW)
+	for _, e := range events {
+		sse.FormatEvent(vrb, e)
+	}
+}
+
+// streamEvents follows a text/event-stream response. If the server closes
+// the connection, the request is resent with the Last-Event-ID header after
+// the reconnection time until the request is cancelled.
+func (a *App) streamEvents(g *gocui.Gui, ctx context.Context, r *Request, client *request.Client, model *request.Request, response *http.Response, tracer *requestTracer) {
+	sse := r.Formatter.(*formatter.SSEFormatter)
+	readErr := a.readResponseBody(g, ctx, r, response.Body)
+	tracer.BodyRead(g, r)
+	for readErr == nil && a.config.General.ReconnectEventStreams {
+		// the formatter is updated by the UI goroutine
+		closed := make(chan struct{})
+		g.UpdateAsync(func(g *gocui.Gui) error {
+			sse.Reset()
+			if a.isDisplayed(r) && getViewValue(g, SEARCH_VIEW) == "" {
+				vrb, _ := g.View(RESPONSE_BODY_VIEW)
+				fmt.Fprintf(vrb, "\x1b[0;31m[!] Connection closed, reconnecting in %v\x1b[0;0m\n\n", sse.Retry())
+			}
+			close(closed)
+			return nil
+		})
+		<-closed
+
+		select {
+		case <-time.After(sse.Retry()):
+		case <-ctx.Done():
+			a.completeResponseBody(g, r, response, context.Cause(ctx))
+			return
+		}
+
+		var reconnected *http.Response
+		reconnected, readErr = a.reconnectEventStream(ctx, client, model, sse.LastEventID(), r.cancel)
+		if readErr != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				readErr = cause
+			}
+			break
+		}
+		if reconnected == nil {
+			// the server asked to stop reconnecting
+			break
+		}
+		readErr = a.readResponseBody(g, ctx, r, reconnected.Body)
+		reconnected.Body.Close()
+	}
+	a.completeResponseBody(g, r, response, readErr)
+}
+
+// reconnectEventStream builds and sends the request of an event stream
+// again. A nil response without error is returned if the server responded
+// with 204 No Content. The stream is cancelled if the server does not
+// respond in time.
+func (a *App) reconnectEventStream(ctx context.Context, client *request.Client, model *request.Request, lastEventID string, cancel context.CancelCauseFunc) (*http.Response, error) {
+	req, err := client.Build(withClientCertificateTarget(ctx), model)
+	if err != nil {
+		return nil, err
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	stopTimeout := a.responseTimeout(cancel)
+	response, err := client.Do(req)
+	stopTimeout()
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNoContent {
+		response.Body.Close()
+		return nil, nil
+	}
+	ctype, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if response.StatusCode != http.StatusOK || ctype != "text/event-stream" {
+		response.Body.Close()
+		return nil, fmt.Errorf("Reconnection failed: %v %v", response.Status, ctype)
+	}
+	return response, nil
+}
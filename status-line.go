@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"text/template"
+	"time"
 
 	"github.com/awesome-gocui/gocui"
 )
@@ -27,6 +28,57 @@ func (s *StatusLineFunctions) Duration() string {
 	return s.app.history[s.app.historyIndex].Duration.String()
 }
 
+func (s *StatusLineFunctions) timing(phase func(t RequestTiming) time.Duration) string {
+	if len(s.app.history) == 0 {
+		return ""
+	}
+	return phase(s.app.history[s.app.historyIndex].Timing).String()
+}
+
+func (s *StatusLineFunctions) DNSLookup() string {
+	return s.timing(func(t RequestTiming) time.Duration { return t.DNSLookup })
+}
+
+func (s *StatusLineFunctions) TCPConnect() string {
+	return s.timing(func(t RequestTiming) time.Duration { return t.TCPConnect })
+}
+
+func (s *StatusLineFunctions) TLSHandshake() string {
+	return s.timing(func(t RequestTiming) time.Duration { return t.TLSHandshake })
+}
+
+func (s *StatusLineFunctions) ServerProcessing() string {
+	return s.timing(func(t RequestTiming) time.Duration { return t.ServerProcessing })
+}
+
+func (s *StatusLineFunctions) TimeToFirstByte() string {
+	return s.timing(func(t RequestTiming) time.Duration { return t.TimeToFirstByte })
+}
+
+func (s *StatusLineFunctions) ContentTransfer() string {
+	return s.timing(func(t RequestTiming) time.Duration { return t.ContentTransfer })
+}
+
+func (s *StatusLineFunctions) BytesReceived() string {
+	if len(s.app.history) == 0 {
+		return ""
+	}
+	return formatSize(len(s.app.history[s.app.historyIndex].RawResponseBody))
+}
+
+func formatSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := unit, 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func (s *StatusLineFunctions) HistorySize() string {
 	return strconv.Itoa(len(s.app.history))
 }
@@ -64,6 +116,62 @@ func (s *StatusLineFunctions) DisableRedirect() string {
 	return "Activated"
 }
 
+func (s *StatusLineFunctions) Environment() string {
+	return s.app.config.General.Environment
+}
+
+// Variables returns the number of captured session variables
+func (s *StatusLineFunctions) Variables() string {
+	if s.app.sessionVariables.len() == 0 {
+		return ""
+	}
+	return strconv.Itoa(s.app.sessionVariables.len())
+}
+
+func (s *StatusLineFunctions) Auth() string {
+	return s.app.config.General.Auth
+}
+
+func (s *StatusLineFunctions) Signing() string {
+	return s.app.config.General.Signing
+}
+
+// Cookies returns the number of cookies if the cookie jar is enabled
+func (s *StatusLineFunctions) Cookies() string {
+	if s.app.cookies == nil {
+		return ""
+	}
+	return strconv.Itoa(len(s.app.cookies.list()))
+}
+
+func (s *StatusLineFunctions) Redirects() string {
+	if len(s.app.history) == 0 {
+		return ""
+	}
+	return redirectCount(s.app.history[s.app.historyIndex])
+}
+
+func (s *StatusLineFunctions) Assertions() string {
+	if len(s.app.history) == 0 {
+		return ""
+	}
+	return assertionSummary(s.app.history[s.app.historyIndex])
+}
+
+func (s *StatusLineFunctions) CertificateWarning() string {
+	if len(s.app.history) == 0 {
+		return ""
+	}
+	return s.app.history[s.app.historyIndex].TLS.ExpiryWarning(s.app.config.General.CertExpiryWarningDays)
+}
+
+func (s *StatusLineFunctions) HistoryError() string {
+	if err := s.app.historyError(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 func NewStatusLine(format string) (*StatusLine, error) {
 	tpl, err := template.New("status line").Parse(format)
 	if err != nil {
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/asciimoo/wuzz/formatter"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+var errRequestCancelled = errors.New("Request cancelled")
+
+const STREAM_CHUNK_SIZE = 32 * 1024
+
+// responseStream buffers the chunks of a response body read in the
+// background until the UI goroutine takes them. Chunks arriving while an
+// update is already scheduled are coalesced into that update.
+type responseStream struct {
+	sync.Mutex
+	pending   []byte
+	scheduled bool
+}
+
+// push buffers a chunk and reports whether an update has to be scheduled
+func (s *responseStream) push(chunk []byte) bool {
+	s.Lock()
+	defer s.Unlock()
+	s.pending = append(s.pending, chunk...)
+	if s.scheduled {
+		return false
+	}
+	s.scheduled = true
+	return true
+}
+
+func (s *responseStream) take() []byte {
+	s.Lock()
+	defer s.Unlock()
+	chunk := s.pending
+	s.pending = nil
+	s.scheduled = false
+	return chunk
+}
+
+// responseTimeout cancels a request if its response headers do not arrive
+// within the configured timeout, the body is streamed until it is complete
+// or the request is cancelled. The returned function stops the timer.
+func (a *App) responseTimeout(cancel context.CancelCauseFunc) func() {
+	timeout := a.config.General.Timeout.Duration
+	if timeout <= 0 {
+		return func() {}
+	}
+	timer := time.AfterFunc(timeout, func() {
+		cancel(fmt.Errorf("No response within %v", timeout))
+	})
+	return func() { timer.Stop() }
+}
+
+// streamResponseBody reads the body into r.RawResponseBody and renders the
+// chunks as they arrive. The response is formatted when the transfer is
+// complete.
+func (a *App) streamResponseBody(g *gocui.Gui, ctx context.Context, r *Request, response *http.Response, tracer *requestTracer) {
+	readErr := a.readResponseBody(g, ctx, r, response.Body)
+	tracer.BodyRead(g, r)
+	a.completeResponseBody(g, r, response, readErr)
+}
+
+// readResponseBody passes the chunks of body to the UI goroutine until the
+// body is read or the request is cancelled
+func (a *App) readResponseBody(g *gocui.Gui, ctx context.Context, r *Request, body io.Reader) error {
+	stream := &responseStream{}
+	buf := make([]byte, STREAM_CHUNK_SIZE)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 && stream.push(buf[:n]) {
+			g.Update(func(g *gocui.Gui) error {
+				a.appendResponseBody(g, r, stream.take())
+				return nil
+			})
+		}
+		if err != nil {
+			g.UpdateAsync(func(g *gocui.Gui) error {
+				a.appendResponseBody(g, r, stream.take())
+				return nil
+			})
+			if err == io.EOF {
+				return nil
+			}
+			if cause := context.Cause(ctx); cause != nil {
+				return cause
+			}
+			return err
+		}
+	}
+}
+
+// completeResponseBody formats the response when the body is read. The
+// headers are formatted again with the trailers received after the body.
+func (a *App) completeResponseBody(g *gocui.Gui, r *Request, response *http.Response, readErr error) {
+	g.UpdateAsync(func(g *gocui.Gui) error {
+		// cancelled or truncated responses capture no variables
+		if readErr == nil {
+			r.ResponseHeaders = formatResponse(response)
+			a.captureVariables(r)
+			refreshStatusLine(a, g)
+		}
+		r.AssertionResults = evaluateAssertions(r)
+		a.SaveHistory(g)
+		a.SaveCookies()
+		if !a.isDisplayed(r) {
+			return nil
+		}
+		vrh, _ := g.View(RESPONSE_HEADERS_VIEW)
+		setViewTextAndCursor(vrh, r.ResponseHeaders)
+		a.printBody(g)
+		if readErr != nil {
+			vrb, _ := g.View(RESPONSE_BODY_VIEW)
+			fmt.Fprintf(vrb, "\n[!] %v", readErr)
+		}
+		return nil
+	})
+}
+
+func (a *App) isDisplayed(r *Request) bool {
+	return len(a.history) > 0 && a.history[a.historyIndex] == r
+}
+
+// appendResponseBody adds a chunk to the body of a request in flight and
+// writes it unformatted into the response view. Binary content is only
+// rendered when the transfer is complete.
+func (a *App) appendResponseBody(g *gocui.Gui, r *Request, chunk []byte) {
+	r.RawResponseBody = append(r.RawResponseBody, chunk...)
+	if sse, ok := r.Formatter.(*formatter.SSEFormatter); ok {
+		a.appendEvents(g, r, sse)
+		return
+	}
+	if !a.isDisplayed(r) || !r.Formatter.Searchable() || getViewValue(g, SEARCH_VIEW) != "" {
+		return
+	}
+	vrb, _ := g.View(RESPONSE_BODY_VIEW)
+	vrb.Write(chunk)
+}
+
+// CancelRequest aborts the request in flight
+func (a *App) CancelRequest(_ *gocui.Gui, _ *gocui.View) error {
+	if a.activeRequest != nil {
+		a.activeRequest.cancel(errRequestCancelled)
+	}
+	return nil
+}
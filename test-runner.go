@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runTests sends the saved requests of collections without the TUI and
+// checks their assertions. It returns the exit code of `wuzz test`.
+func runTests(configPath string, args []string) int {
+	a := &App{}
+	if err := a.LoadConfig(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+		return 2
+	}
+	collections, err := a.parseTestArgs(args)
+	if err == nil {
+		err = a.InitConfig()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error!", err)
+		return 2
+	}
+
+	var locations []string
+	for _, collection := range collections {
+		requests, err := a.collectionRequests(collection)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error!", err)
+			return 2
+		}
+		locations = append(locations, requests...)
+	}
+
+	failedRequests, assertions, failedAssertions := 0, 0, 0
+	for _, location := range locations {
+		label := location
+		if rel, err := filepath.Rel(a.collectionsDir(), location); err == nil && !strings.HasPrefix(rel, "..") {
+			label = strings.TrimSuffix(rel, COLLECTION_EXTENSION)
+		}
+		r, err := loadStoredRequest(location)
+		if err == nil {
+			err = a.sendRequest(r)
+		}
+		if err != nil {
+			failedRequests++
+			fmt.Printf("FAIL %v: %v\n", label, err)
+			continue
+		}
+		failed := 0
+		for _, result := range r.AssertionResults {
+			if !result.Passed {
+				failed++
+			}
+		}
+		status := "PASS"
+		if failed > 0 {
+			status = "FAIL"
+			failedRequests++
+		}
+		fmt.Printf("%v %v (%v %v, %v, %v)\n", status, label, r.Method, r.Url, r.StatusCode, r.Duration.Round(time.Millisecond))
+		for _, result := range r.AssertionResults {
+			if result.Passed {
+				fmt.Printf("  PASS %v\n", result.Assertion)
+			} else {
+				fmt.Printf("  FAIL %v: %v\n", result.Assertion, result.Message)
+			}
+		}
+		for _, result := range r.CaptureResults {
+			if result.Message != "" {
+				fmt.Printf("  MISS %v: %v\n", result.Name, result.Message)
+			}
+		}
+		assertions += len(r.AssertionResults)
+		failedAssertions += failed
+	}
+
+	fmt.Printf("\n%d requests, %d failed, %d of %d assertions passed\n", len(locations), failedRequests, assertions-failedAssertions, assertions)
+	if failedRequests > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (a *App) parseTestArgs(args []string) ([]string, error) {
+	var collections []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--env":
+			if i == len(args)-1 {
+				return nil, errors.New("No environment specified")
+			}
+			i++
+			if _, found := a.config.Environments[args[i]]; !found {
+				return nil, fmt.Errorf("Unknown environment: %v", args[i])
+			}
+			a.config.General.Environment = args[i]
+		case "-k", "--insecure":
+			a.config.General.Insecure = true
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				return nil, fmt.Errorf("Unknown option: %v", args[i])
+			}
+			collections = append(collections, args[i])
+		}
+	}
+	if len(collections) == 0 {
+		return nil, errors.New("No collection specified")
+	}
+	return collections, nil
+}
+
+// collectionRequests lists the saved requests of a collection folder or a
+// single saved request. Names are looked up in the collections directory
+// first.
+func (a *App) collectionRequests(name string) ([]string, error) {
+	location := a.collectionPath(name)
+	if _, err := os.Stat(location); err != nil {
+		location += COLLECTION_EXTENSION
+	}
+	if _, err := os.Stat(location); err != nil {
+		location = name
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown collection: %v", name)
+	}
+	if !info.IsDir() {
+		return []string{location}, nil
+	}
+	var requests []string
+	err = filepath.Walk(location, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(p) == COLLECTION_EXTENSION {
+			requests = append(requests, p)
+		}
+		return nil
+	})
+	return requests, err
+}
+
+// loadStoredRequest reads a request saved in the JSON format
+func loadStoredRequest(location string) (*Request, error) {
+	requestJson, err := os.ReadFile(location)
+	if err != nil {
+		return nil, fmt.Errorf("File reading error: %v", err)
+	}
+	var requestMap map[string]string
+	if err := json.Unmarshal(requestJson, &requestMap); err != nil {
+		return nil, fmt.Errorf("JSON decoding error: %v", err)
+	}
+	variables, graphQL := requestMap[GRAPHQL_VARIABLES_VIEW]
+	r := &Request{
+		Url:        requestMap[URL_VIEW],
+		Method:     requestMap[REQUEST_METHOD_VIEW],
+		GetParams:  requestMap[URL_PARAMS_VIEW],
+		Data:       requestMap[REQUEST_DATA_VIEW],
+		Headers:    requestMap[REQUEST_HEADERS_VIEW],
+		GraphQL:    graphQL,
+		Variables:  variables,
+		Auth:       requestMap[AUTH_VIEW],
+		Signing:    requestMap[SIGNING_VIEW],
+		Assertions: requestMap[ASSERTIONS_VIEW],
+		Captures:   requestMap[CAPTURES_VIEW],
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	return r, nil
+}
+
+// applyStoredRequest replaces the parts of r set in a saved request and
+// selects the auth, signing, assertions and captures of the saved request
+func (a *App) applyStoredRequest(r, stored *Request) {
+	if stored.Url != "" {
+		r.Url = stored.Url
+	}
+	r.Method = stored.Method
+	if stored.GetParams != "" {
+		r.GetParams = stored.GetParams
+	}
+	if stored.Data != "" {
+		r.Data = stored.Data
+	}
+	if stored.Headers != "" {
+		r.Headers = stored.Headers
+	}
+	r.GraphQL = stored.GraphQL
+	if stored.GraphQL {
+		r.Variables = stored.Variables
+	}
+	a.config.General.Auth = stored.Auth
+	a.config.General.Signing = stored.Signing
+	a.assertions = stored.Assertions
+	a.captures = stored.Captures
+}
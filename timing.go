@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// RequestTiming holds the phases of a request measured with httptrace.
+// Phases which did not happen (e.g. DNS lookup and connect on a reused
+// connection) are zero. The phases of redirected requests are the ones of
+// the last request.
+type RequestTiming struct {
+	DNSLookup        time.Duration
+	TCPConnect       time.Duration
+	TLSHandshake     time.Duration
+	ServerProcessing time.Duration
+	TimeToFirstByte  time.Duration
+	ContentTransfer  time.Duration
+}
+
+// requestTracer measures the phases of a request. The trace hooks are
+// called from the goroutines of the transport, e.g. the dials of several
+// addresses run in parallel.
+type requestTracer struct {
+	sync.Mutex
+	timing       RequestTiming
+	start        time.Time
+	dnsStart     time.Time
+	connectStart map[string]time.Time
+	// durations of the successful dials by address
+	connects     map[string]time.Duration
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func newRequestTracer(start time.Time) *requestTracer {
+	return &requestTracer{start: start}
+}
+
+func (t *requestTracer) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(_ string) {
+			t.Lock()
+			defer t.Unlock()
+			// every redirected request measures its connection again
+			t.timing.DNSLookup, t.timing.TCPConnect, t.timing.TLSHandshake = 0, 0, 0
+			t.connectStart = make(map[string]time.Time)
+			t.connects = make(map[string]time.Duration)
+		},
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.timing.DNSLookup = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(_, addr string) {
+			t.Lock()
+			defer t.Unlock()
+			if t.connectStart != nil {
+				t.connectStart[addr] = time.Now()
+			}
+		},
+		ConnectDone: func(_, addr string, err error) {
+			t.Lock()
+			defer t.Unlock()
+			if start, found := t.connectStart[addr]; found && err == nil {
+				t.connects[addr] = time.Since(start)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.Lock()
+			defer t.Unlock()
+			if info.Reused || info.Conn == nil {
+				return
+			}
+			// only the dial of the used connection is recorded
+			if d, found := t.connects[info.Conn.RemoteAddr().String()]; found {
+				t.timing.TCPConnect = d
+				return
+			}
+			// the address is unknown with proxies, e.g. SOCKS
+			for _, d := range t.connects {
+				t.timing.TCPConnect = max(t.timing.TCPConnect, d)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.Lock()
+			defer t.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			t.Lock()
+			defer t.Unlock()
+			t.timing.TLSHandshake = time.Since(t.tlsStart)
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.Lock()
+			defer t.Unlock()
+			t.firstByte = time.Now()
+			t.timing.ServerProcessing = t.firstByte.Sub(t.wroteRequest)
+			t.timing.TimeToFirstByte = t.firstByte.Sub(t.start)
+		},
+	}
+}
+
+// Timing returns the phases measured so far
+func (t *requestTracer) Timing() RequestTiming {
+	t.Lock()
+	defer t.Unlock()
+	return t.timing
+}
+
+// BodyRead records the end of the content transfer and sets the timing and
+// the duration of the request on the UI goroutine. The duration includes
+// the transfer of the body.
+func (t *requestTracer) BodyRead(g *gocui.Gui, r *Request) {
+	t.Lock()
+	if !t.firstByte.IsZero() {
+		t.timing.ContentTransfer = time.Since(t.firstByte)
+	}
+	timing := t.timing
+	t.Unlock()
+	duration := time.Since(t.start)
+	g.UpdateAsync(func(g *gocui.Gui) error {
+		r.Timing = timing
+		r.Duration = duration
+		return nil
+	})
+}
+
+func (a *App) ToggleTiming(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == TIMING_VIEW {
+		a.closePopup(g, TIMING_VIEW)
+		return nil
+	}
+
+	height := 8
+	if len(a.history) > 0 && len(a.history[a.historyIndex].Redirects) > 0 {
+		height += 2
+	}
+	timing, err := a.CreatePopupView(TIMING_VIEW, 70, height, g)
+	if err != nil {
+		return err
+	}
+	timing.Title = VIEW_TITLES[TIMING_VIEW]
+	timing.Highlight = false
+	g.SetViewOnTop(TIMING_VIEW)
+	g.SetCurrentView(TIMING_VIEW)
+
+	if len(a.history) == 0 {
+		setViewTextAndCursor(timing, "[!] No request sent yet")
+		return nil
+	}
+	r := a.history[a.historyIndex]
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"DNS lookup", r.Timing.DNSLookup},
+		{"TCP connect", r.Timing.TCPConnect},
+		{"TLS handshake", r.Timing.TLSHandshake},
+		{"Server processing", r.Timing.ServerProcessing},
+		{"Content transfer", r.Timing.ContentTransfer},
+	}
+	var total time.Duration
+	for _, p := range phases {
+		total += p.duration
+	}
+	const barWidth = 40
+	offset := 0
+	for _, p := range phases {
+		width := 0
+		if total > 0 {
+			width = int(int64(barWidth) * int64(p.duration) / int64(total))
+		}
+		fmt.Fprintf(timing, "%-18v %10v %v\x1b[0;32m%v\x1b[0;0m\n", p.name, p.duration.Round(time.Microsecond), strings.Repeat(" ", offset), strings.Repeat("█", width))
+		offset += width
+	}
+	fmt.Fprintf(timing, "%-18v %10v\n", "Time to first byte", r.Timing.TimeToFirstByte.Round(time.Microsecond))
+	fmt.Fprintf(timing, "%-18v %10v\n", "Total", total.Round(time.Microsecond))
+	if len(r.Redirects) > 0 {
+		fmt.Fprintf(timing, "\n[!] Phases of the last of %d redirected requests\n", len(r.Redirects)+1)
+	}
+	return nil
+}
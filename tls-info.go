@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// TLSInfo describes the TLS connection of a response
+type TLSInfo struct {
+	Version      string
+	CipherSuite  string
+	ALPN         string
+	Resumed      bool
+	ServerName   string
+	Certificates []CertificateInfo
+}
+
+type CertificateInfo struct {
+	Subject           string
+	Issuer            string
+	SANs              []string
+	SerialNumber      string
+	NotBefore         time.Time
+	NotAfter          time.Time
+	SHA1Fingerprint   string
+	SHA256Fingerprint string
+}
+
+func newTLSInfo(cs *tls.ConnectionState) *TLSInfo {
+	if cs == nil {
+		return nil
+	}
+	info := &TLSInfo{
+		Version:      tls.VersionName(cs.Version),
+		CipherSuite:  tls.CipherSuiteName(cs.CipherSuite),
+		ALPN:         cs.NegotiatedProtocol,
+		Resumed:      cs.DidResume,
+		ServerName:   cs.ServerName,
+		Certificates: make([]CertificateInfo, 0, len(cs.PeerCertificates)),
+	}
+	for _, cert := range cs.PeerCertificates {
+		info.Certificates = append(info.Certificates, newCertificateInfo(cert))
+	}
+	return info
+}
+
+func newCertificateInfo(cert *x509.Certificate) CertificateInfo {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	return CertificateInfo{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SANs:              sans,
+		SerialNumber:      cert.SerialNumber.String(),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		SHA1Fingerprint:   fingerprint(sha1Sum[:]),
+		SHA256Fingerprint: fingerprint(sha256Sum[:]),
+	}
+}
+
+func fingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ExpiryWarning returns a warning if a certificate of the chain expires
+// within the given number of days
+func (t *TLSInfo) ExpiryWarning(days int) string {
+	if t == nil || days <= 0 {
+		return ""
+	}
+	for _, cert := range t.Certificates {
+		if warning := cert.ExpiryWarning(days); warning != "" {
+			return warning
+		}
+	}
+	return ""
+}
+
+func (c CertificateInfo) ExpiryWarning(days int) string {
+	left := time.Until(c.NotAfter)
+	switch {
+	case left < 0:
+		return "certificate expired"
+	case left < time.Duration(days)*24*time.Hour:
+		return fmt.Sprintf("certificate expires in %d days", int(left.Hours()/24))
+	}
+	return ""
+}
+
+func (t *TLSInfo) Print(w io.Writer, warningDays int) {
+	fmt.Fprintf(w, "\x1b[0;33mVersion:\x1b[0;0m      %v\n", t.Version)
+	fmt.Fprintf(w, "\x1b[0;33mCipher suite:\x1b[0;0m %v\n", t.CipherSuite)
+	fmt.Fprintf(w, "\x1b[0;33mALPN:\x1b[0;0m         %v\n", t.ALPN)
+	fmt.Fprintf(w, "\x1b[0;33mServer name:\x1b[0;0m  %v\n", t.ServerName)
+	fmt.Fprintf(w, "\x1b[0;33mResumed:\x1b[0;0m      %v\n", t.Resumed)
+	for i, cert := range t.Certificates {
+		fmt.Fprintf(w, "\n\x1b[0;32mCertificate #%d\x1b[0;0m\n", i)
+		fmt.Fprintf(w, "  Subject:    %v\n", cert.Subject)
+		fmt.Fprintf(w, "  Issuer:     %v\n", cert.Issuer)
+		if len(cert.SANs) > 0 {
+			fmt.Fprintf(w, "  SANs:       %v\n", strings.Join(cert.SANs, ", "))
+		}
+		fmt.Fprintf(w, "  Serial:     %v\n", cert.SerialNumber)
+		fmt.Fprintf(w, "  Not before: %v\n", cert.NotBefore.Format(time.RFC1123))
+		fmt.Fprintf(w, "  Not after:  %v\n", cert.NotAfter.Format(time.RFC1123))
+		if warning := cert.ExpiryWarning(warningDays); warning != "" {
+			fmt.Fprintf(w, "  \x1b[0;31mWarning: %v\x1b[0;0m\n", warning)
+		}
+		fmt.Fprintf(w, "  SHA-1:      %v\n", cert.SHA1Fingerprint)
+		fmt.Fprintf(w, "  SHA-256:    %v\n", cert.SHA256Fingerprint)
+	}
+}
+
+func (a *App) ToggleTLSInfo(g *gocui.Gui, _ *gocui.View) error {
+	// Destroy if present
+	if a.currentPopup == TLS_INFO_VIEW {
+		a.closePopup(g, TLS_INFO_VIEW)
+		return nil
+	}
+
+	maxX, maxY := g.Size()
+	info, err := a.CreatePopupView(TLS_INFO_VIEW, maxX-4, maxY-4, g)
+	if err != nil {
+		return err
+	}
+	info.Title = VIEW_TITLES[TLS_INFO_VIEW]
+	info.Highlight = false
+	info.Wrap = true
+	g.SetViewOnTop(TLS_INFO_VIEW)
+	g.SetCurrentView(TLS_INFO_VIEW)
+
+	if len(a.history) == 0 || a.history[a.historyIndex].TLS == nil {
+		setViewTextAndCursor(info, "[!] No TLS connection")
+		return nil
+	}
+	a.history[a.historyIndex].TLS.Print(info, a.config.General.CertExpiryWarningDays)
+	return nil
+}
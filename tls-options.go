@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+var TLS_CURVES = map[string]tls.CurveID{
+	"P-256":          tls.CurveP256,
+	"P-384":          tls.CurveP384,
+	"P-521":          tls.CurveP521,
+	"X25519":         tls.X25519,
+	"X25519MLKEM768": tls.X25519MLKEM768,
+	// OpenSSL names used by curl
+	"prime256v1": tls.CurveP256,
+	"secp384r1":  tls.CurveP384,
+	"secp521r1":  tls.CurveP521,
+}
+
+// splitTLSList splits comma or colon (curl style) separated values
+func splitTLSList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ':' || r == ' '
+	})
+}
+
+// cipherSuiteIDs resolves the names of cipher suites (e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256). Insecure suites are allowed to
+// be able to test servers accepting them.
+func cipherSuiteIDs(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	suites := make(map[string]uint16)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[s.Name] = s.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, found := suites[strings.ToUpper(name)]
+		if !found {
+			return nil, fmt.Errorf("Unknown cipher suite: %v", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func curveIDs(names []string) ([]tls.CurveID, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids := make([]tls.CurveID, 0, len(names))
+	for _, name := range names {
+		var id tls.CurveID
+		found := false
+		for curveName, curveID := range TLS_CURVES {
+			if strings.EqualFold(curveName, name) {
+				id, found = curveID, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown curve: %v", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
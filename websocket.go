@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asciimoo/wuzz/config"
+	"github.com/asciimoo/wuzz/formatter"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// websocket protocol, see https://tools.ietf.org/html/rfc6455
+
+const WEBSOCKET_GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WEBSOCKET_MAX_MESSAGE_SIZE limits the size of received frames and
+// reassembled messages
+const WEBSOCKET_MAX_MESSAGE_SIZE = 16 * 1024 * 1024
+
+const (
+	WS_CONTINUATION = 0x0
+	WS_TEXT         = 0x1
+	WS_BINARY       = 0x2
+	WS_CLOSE        = 0x8
+	WS_PING         = 0x9
+	WS_PONG         = 0xA
+)
+
+var WS_OPCODE_NAMES = map[int]string{
+	WS_TEXT:   "text",
+	WS_BINARY: "binary",
+	WS_CLOSE:  "close",
+	WS_PING:   "ping",
+	WS_PONG:   "pong",
+}
+
+var WS_CLOSE_CODES = map[int]string{
+	1000: "Normal Closure",
+	1001: "Going Away",
+	1002: "Protocol Error",
+	1003: "Unsupported Data",
+	1005: "No Status Received",
+	1006: "Abnormal Closure",
+	1007: "Invalid Payload Data",
+	1008: "Policy Violation",
+	1009: "Message Too Big",
+	1010: "Mandatory Extension",
+	1011: "Internal Error",
+	1012: "Service Restart",
+	1013: "Try Again Later",
+	1014: "Bad Gateway",
+	1015: "TLS Handshake",
+}
+
+// WebSocketMessage is a sent or received message of a websocket session.
+// Control frames are recorded as messages as well.
+type WebSocketMessage struct {
+	Timestamp time.Time
+	Outgoing  bool
+	Opcode    int
+	Data      []byte
+	CloseCode int `json:",omitempty"`
+}
+
+func (m *WebSocketMessage) contentType() string {
+	if json.Valid(m.Data) {
+		return config.ContentTypes["json"]
+	}
+	if m.Opcode == WS_TEXT {
+		return "text/plain"
+	}
+	return "binary/octet-stream"
+}
+
+// isWebSocketURL reports whether the scheme of the URL is ws or wss
+func isWebSocketURL(u string) bool {
+	return strings.HasPrefix(u, "ws://") || strings.HasPrefix(u, "wss://")
+}
+
+// webSocketHandshake sets the upgrade headers of the request and returns
+// the expected Sec-WebSocket-Accept value
+func webSocketHandshake(req *http.Request) (string, error) {
+	switch req.URL.Scheme {
+	case "ws":
+		req.URL.Scheme = "http"
+	case "wss":
+		req.URL.Scheme = "https"
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	return webSocketAccept(key), nil
+}
+
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + WEBSOCKET_GUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+var errWebSocketMessageTooBig = errors.New("Message too big")
+
+// webSocketConn reads and writes the frames of an upgraded connection
+type webSocketConn struct {
+	conn   io.ReadWriteCloser
+	reader *bufio.Reader
+	// message being reassembled from fragments
+	fragment *WebSocketMessage
+	sync.Mutex
+}
+
+func newWebSocketConn(conn io.ReadWriteCloser) *webSocketConn {
+	return &webSocketConn{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// WriteFrame sends a masked frame
+func (c *webSocketConn) WriteFrame(opcode int, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|byte(opcode))
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.Lock()
+	defer c.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// readFrame reads the next frame. Frames over WEBSOCKET_MAX_MESSAGE_SIZE,
+// including the fragments received before a continuation frame, are
+// rejected before the payload is read.
+func (c *webSocketConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.reader, header); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(c.reader, ext); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(c.reader, ext); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	limit := uint64(WEBSOCKET_MAX_MESSAGE_SIZE)
+	if opcode == WS_CONTINUATION && c.fragment != nil {
+		limit -= uint64(len(c.fragment.Data))
+	}
+	if length > limit {
+		err = errWebSocketMessageTooBig
+		return
+	}
+	mask := make([]byte, 4)
+	if masked {
+		if _, err = io.ReadFull(c.reader, mask); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// ReadMessage returns the next data message or control frame, fragmented
+// messages are reassembled
+func (c *webSocketConn) ReadMessage() (*WebSocketMessage, error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case opcode >= WS_CLOSE:
+			// control frames can be interleaved with fragments
+			m := &WebSocketMessage{Timestamp: time.Now(), Opcode: opcode, Data: payload}
+			if opcode == WS_CLOSE {
+				m.CloseCode = 1005
+				if len(payload) >= 2 {
+					m.CloseCode = int(binary.BigEndian.Uint16(payload))
+					m.Data = payload[2:]
+				}
+			}
+			return m, nil
+		case opcode == WS_CONTINUATION:
+			if c.fragment == nil {
+				return nil, errors.New("Unexpected continuation frame")
+			}
+			c.fragment.Data = append(c.fragment.Data, payload...)
+		default:
+			c.fragment = &WebSocketMessage{Timestamp: time.Now(), Opcode: opcode, Data: payload}
+		}
+		if fin {
+			message := c.fragment
+			c.fragment = nil
+			return message, nil
+		}
+	}
+}
+
+func (c *webSocketConn) Close() error {
+	return c.conn.Close()
+}
+
+// webSocketSession is the websocket connection of the displayed request
+type webSocketSession struct {
+	conn    *webSocketConn
+	request *Request
+	binary  bool
+}
+
+// runWebSocket reads the messages of an upgraded connection until it is
+// closed or the request is cancelled
+func (a *App) runWebSocket(g *gocui.Gui, ctx context.Context, r *Request, response *http.Response, accept string) error {
+	if !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") || response.Header.Get("Sec-WebSocket-Accept") != accept {
+		response.Body.Close()
+		return errors.New("Invalid websocket handshake")
+	}
+	rwc, ok := response.Body.(io.ReadWriteCloser)
+	if !ok {
+		response.Body.Close()
+		return errors.New("Connection cannot be upgraded")
+	}
+	conn := newWebSocketConn(rwc)
+	defer conn.Close()
+	session := &webSocketSession{conn: conn, request: r}
+	g.UpdateAsync(func(g *gocui.Gui) error {
+		a.openWebSocketSession(g, session)
+		return nil
+	})
+	defer g.UpdateAsync(func(g *gocui.Gui) error {
+		a.closeWebSocketSession(g, session)
+		return nil
+	})
+
+	// closing handshake initiated by cancelling the request
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-closed:
+			return
+		}
+		a.sendWebSocketMessage(g, session, &WebSocketMessage{Opcode: WS_CLOSE, CloseCode: 1000})
+		select {
+		case <-time.After(3 * time.Second):
+			conn.Close()
+		case <-closed:
+		}
+	}()
+
+	for {
+		m, err := conn.ReadMessage()
+		if err != nil {
+			if err == errWebSocketMessageTooBig && context.Cause(ctx) == nil {
+				a.sendWebSocketMessage(g, session, &WebSocketMessage{Opcode: WS_CLOSE, CloseCode: 1009})
+			}
+			if cause := context.Cause(ctx); cause != nil {
+				return cause
+			}
+			return err
+		}
+		g.UpdateAsync(func(g *gocui.Gui) error {
+			a.appendWebSocketMessage(g, r, m)
+			return nil
+		})
+		switch m.Opcode {
+		case WS_PING:
+			a.sendWebSocketMessage(g, session, &WebSocketMessage{Opcode: WS_PONG, Data: m.Data})
+		case WS_CLOSE:
+			if context.Cause(ctx) == nil {
+				a.sendWebSocketMessage(g, session, &WebSocketMessage{Opcode: WS_CLOSE, CloseCode: m.CloseCode})
+			}
+			return nil
+		}
+	}
+}
+
+// sendWebSocketMessage writes a message and adds it to the log
+func (a *App) sendWebSocketMessage(g *gocui.Gui, s *webSocketSession, m *WebSocketMessage) error {
+	m.Outgoing = true
+	m.Timestamp = time.Now()
+	payload := m.Data
+	if m.Opcode == WS_CLOSE && m.CloseCode != 1005 {
+		payload = binary.BigEndian.AppendUint16(nil, uint16(m.CloseCode))
+		payload = append(payload, m.Data...)
+	}
+	err := s.conn.WriteFrame(m.Opcode, payload)
+	if err != nil {
+		return err
+	}
+	g.UpdateAsync(func(g *gocui.Gui) error {
+		a.appendWebSocketMessage(g, s.request, m)
+		return nil
+	})
+	return nil
+}
+
+func (a *App) appendWebSocketMessage(g *gocui.Gui, r *Request, m *WebSocketMessage) {
+	r.Messages = append(r.Messages, m)
+	if !a.isDisplayed(r) {
+		return
+	}
+	if getViewValue(g, SEARCH_VIEW) != "" {
+		a.printBody(g)
+		return
+	}
+	vrb, _ := g.View(RESPONSE_BODY_VIEW)
+	r.Formatter.(*webSocketLog).formatMessage(vrb, m)
+}
+
+func (a *App) openWebSocketSession(g *gocui.Gui, s *webSocketSession) {
+	// the session of the previous request may not be closed yet
+	if a.webSocket == nil {
+		VIEWS = append(VIEWS, WEBSOCKET_INPUT_VIEW)
+	}
+	a.webSocket = s
+	a.Layout(g)
+	a.setViewByName(g, WEBSOCKET_INPUT_VIEW)
+}
+
+func (a *App) closeWebSocketSession(g *gocui.Gui, s *webSocketSession) {
+	if a.webSocket != s {
+		return
+	}
+	a.webSocket = nil
+	VIEWS = VIEWS[:len(VIEWS)-1]
+	focused := g.CurrentView() != nil && g.CurrentView().Name() == WEBSOCKET_INPUT_VIEW
+	g.DeleteView(WEBSOCKET_INPUT_VIEW)
+	if a.viewIndex >= len(VIEWS) {
+		a.viewIndex = 0
+	}
+	if focused {
+		a.setView(g)
+	}
+	a.Layout(g)
+}
+
+// layoutWebSocket shrinks the response body view and shows the message
+// input line while a websocket session is open
+func (a *App) layoutWebSocket(g *gocui.Gui) error {
+	if a.webSocket == nil {
+		return nil
+	}
+	if _, err := setViewAt(g, RESPONSE_BODY_VIEW, WEBSOCKET_LOG_POSITION); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	v, err := setView(g, WEBSOCKET_INPUT_VIEW)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		setViewProperties(v, WEBSOCKET_INPUT_VIEW)
+	}
+	v.Title = a.webSocketInputTitle()
+	return nil
+}
+
+func (a *App) webSocketInputTitle() string {
+	mode := "text"
+	if a.webSocket.binary {
+		mode = "binary, hex encoded"
+	}
+	return fmt.Sprintf("%v [%v]", VIEW_PROPERTIES[WEBSOCKET_INPUT_VIEW].title, mode)
+}
+
+// SendWebSocketMessage sends the content of the input line as a text or a
+// binary message
+func (a *App) SendWebSocketMessage(g *gocui.Gui, v *gocui.View) error {
+	if a.webSocket == nil {
+		return nil
+	}
+	input := getViewValue(g, WEBSOCKET_INPUT_VIEW)
+	m := &WebSocketMessage{Opcode: WS_TEXT, Data: []byte(a.substituteVariables(input))}
+	if a.webSocket.binary {
+		data, err := hex.DecodeString(strings.Join(strings.Fields(input), ""))
+		if err != nil {
+			return a.OpenSaveResultView("Invalid hex data: "+err.Error(), g)
+		}
+		m = &WebSocketMessage{Opcode: WS_BINARY, Data: data}
+	}
+	if err := a.sendWebSocketMessage(g, a.webSocket, m); err != nil {
+		return a.OpenSaveResultView("Websocket error: "+err.Error(), g)
+	}
+	if vi, err := g.View(WEBSOCKET_INPUT_VIEW); err == nil {
+		setViewTextAndCursor(vi, "")
+	}
+	return nil
+}
+
+func (a *App) SendWebSocketPing(g *gocui.Gui, _ *gocui.View) error {
+	if a.webSocket == nil {
+		return nil
+	}
+	if err := a.sendWebSocketMessage(g, a.webSocket, &WebSocketMessage{Opcode: WS_PING, Data: []byte("wuzz")}); err != nil {
+		return a.OpenSaveResultView("Websocket error: "+err.Error(), g)
+	}
+	return nil
+}
+
+func (a *App) ToggleWebSocketBinary(g *gocui.Gui, _ *gocui.View) error {
+	if a.webSocket == nil {
+		return nil
+	}
+	a.webSocket.binary = !a.webSocket.binary
+	return a.layoutWebSocket(g)
+}
+
+// webSocketLog renders the messages of a websocket session in the response
+// body view, data messages are formatted by their content
+type webSocketLog struct {
+	config  *config.Config
+	request *Request
+}
+
+func (l *webSocketLog) Format(writer io.Writer, _ []byte) error {
+	for _, m := range l.request.Messages {
+		l.formatMessage(writer, m)
+	}
+	return nil
+}
+
+func (l *webSocketLog) formatMessage(writer io.Writer, m *WebSocketMessage) {
+	direction := "\x1b[0;34m<-\x1b[0;0m"
+	if m.Outgoing {
+		direction = "\x1b[0;32m->\x1b[0;0m"
+	}
+	fmt.Fprintf(writer, "\x1b[0;36m[%v]\x1b[0;0m %v \x1b[0;33m%v\x1b[0;0m", m.Timestamp.Format("15:04:05.000"), direction, WS_OPCODE_NAMES[m.Opcode])
+	switch m.Opcode {
+	case WS_TEXT, WS_BINARY:
+		fmt.Fprintf(writer, " (%v)\n", formatSize(len(m.Data)))
+		if err := formatter.New(l.config, m.contentType()).Format(writer, m.Data); err != nil {
+			writer.Write(m.Data)
+		}
+		fmt.Fprint(writer, "\n")
+	case WS_CLOSE:
+		fmt.Fprintf(writer, " %v %v %s\n", m.CloseCode, WS_CLOSE_CODES[m.CloseCode], m.Data)
+	default:
+		fmt.Fprintf(writer, " %s\n", m.Data)
+	}
+	fmt.Fprint(writer, "\n")
+}
+
+func (l *webSocketLog) Title() string {
+	return "[websocket]"
+}
+
+func (l *webSocketLog) Searchable() bool {
+	return true
+}
+
+// Search returns the messages matching the regular expression
+func (l *webSocketLog) Search(q string, _ []byte) ([]string, error) {
+	search_re, err := regexp.Compile(q)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, 16)
+	for _, m := range l.request.Messages {
+		if !search_re.Match(m.Data) && !search_re.MatchString(WS_OPCODE_NAMES[m.Opcode]) {
+			continue
+		}
+		buf := &strings.Builder{}
+		l.formatMessage(buf, m)
+		ret = append(ret, strings.TrimRight(buf.String(), "\n"))
+	}
+	return ret, nil
+}
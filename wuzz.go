@@ -2,19 +2,20 @@ package main
 
 import (
 	"bytes"
-	"compress/gzip"
+	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
-	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
-	"path"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"sort"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/asciimoo/wuzz/config"
 	"github.com/asciimoo/wuzz/formatter"
+	"github.com/asciimoo/wuzz/request"
 
 	"github.com/alessio/shellescape"
 	"github.com/awesome-gocui/gocui"
@@ -34,48 +36,83 @@ import (
 
 const VERSION = "0.5.0"
 
-const TIMEOUT_DURATION = 5 // in seconds
 const WINDOWS_OS = "windows"
 const SEARCH_PROMPT = "search> "
 
 const (
 	ALL_VIEWS = ""
 
-	URL_VIEW              = "url"
-	URL_PARAMS_VIEW       = "get"
-	REQUEST_METHOD_VIEW   = "method"
-	REQUEST_DATA_VIEW     = "data"
-	REQUEST_HEADERS_VIEW  = "headers"
-	STATUSLINE_VIEW       = "status-line"
-	SEARCH_VIEW           = "search"
-	RESPONSE_HEADERS_VIEW = "response-headers"
-	RESPONSE_BODY_VIEW    = "response-body"
+	URL_VIEW               = "url"
+	URL_PARAMS_VIEW        = "get"
+	REQUEST_METHOD_VIEW    = "method"
+	REQUEST_DATA_VIEW      = "data"
+	REQUEST_HEADERS_VIEW   = "headers"
+	STATUSLINE_VIEW        = "status-line"
+	SEARCH_VIEW            = "search"
+	RESPONSE_HEADERS_VIEW  = "response-headers"
+	RESPONSE_BODY_VIEW     = "response-body"
+	WEBSOCKET_INPUT_VIEW   = "websocket-input"
+	GRAPHQL_VARIABLES_VIEW = "graphql-variables"
 
 	SEARCH_PROMPT_VIEW              = "prompt"
 	POPUP_VIEW                      = "popup_view"
 	AUTOCOMPLETE_VIEW               = "autocomplete_view"
 	ERROR_VIEW                      = "error_view"
 	HISTORY_VIEW                    = "history"
+	TIMING_VIEW                     = "timing"
+	TLS_INFO_VIEW                   = "tls-info"
+	IMPORT_CURL_DIALOG_VIEW         = "import-curl-dialog"
+	COLLECTIONS_VIEW                = "collections"
 	SAVE_DIALOG_VIEW                = "save-dialog"
 	SAVE_RESPONSE_DIALOG_VIEW       = "save-response-dialog"
 	LOAD_REQUEST_DIALOG_VIEW        = "load-request-dialog"
+	LOAD_HAR_DIALOG_VIEW            = "load-har-dialog"
 	SAVE_REQUEST_FORMAT_DIALOG_VIEW = "save-request-format-dialog"
 	SAVE_REQUEST_DIALOG_VIEW        = "save-request-dialog"
 	SAVE_RESULT_VIEW                = "save-result"
 	METHOD_LIST_VIEW                = "method-list"
+	GRPC_METHODS_VIEW               = "grpc-methods"
+	AUTH_VIEW                       = "auth"
+	SIGNING_VIEW                    = "signing"
+	SENT_REQUEST_VIEW               = "sent-request"
+	COOKIES_VIEW                    = "cookies"
+	REDIRECTS_VIEW                  = "redirects"
+	DIFF_VIEW                       = "diff"
+	RESPONSE_DIFF_VIEW              = "response-diff"
+	ASSERTIONS_VIEW                 = "assertions"
+	ASSERTION_RESULTS_VIEW          = "assertion-results"
+	CAPTURES_VIEW                   = "captures"
+	SESSION_VARIABLES_VIEW          = "session-variables"
 	HELP_VIEW                       = "help"
 )
 
 var VIEW_TITLES = map[string]string{
 	POPUP_VIEW:                      "Info",
 	ERROR_VIEW:                      "Error",
-	HISTORY_VIEW:                    "History",
+	HISTORY_VIEW:                    "History (enter: restore, r: replay)",
+	TIMING_VIEW:                     "Timing",
+	TLS_INFO_VIEW:                   "TLS connection",
+	IMPORT_CURL_DIALOG_VIEW:         "Import curl command (ctrl+r to import, ctrl+q to cancel)",
+	COLLECTIONS_VIEW:                "Collections (enter: open, n: new folder, r: rename, d: duplicate, del: delete)",
 	SAVE_RESPONSE_DIALOG_VIEW:       "Save Response (enter to submit, ctrl+q to cancel)",
 	LOAD_REQUEST_DIALOG_VIEW:        "Load Request (enter to submit, ctrl+q to cancel)",
+	LOAD_HAR_DIALOG_VIEW:            "Load HAR (enter to submit, ctrl+q to cancel)",
 	SAVE_REQUEST_DIALOG_VIEW:        "Save Request (enter to submit, ctrl+q to cancel)",
 	SAVE_REQUEST_FORMAT_DIALOG_VIEW: "Choose export format",
 	SAVE_RESULT_VIEW:                "Save Result (press enter to close)",
 	METHOD_LIST_VIEW:                "Methods",
+	GRPC_METHODS_VIEW:               "gRPC methods (enter: select)",
+	AUTH_VIEW:                       "Auth (enter: select)",
+	SIGNING_VIEW:                    "Request signing (enter: select)",
+	SENT_REQUEST_VIEW:               "Sent request",
+	COOKIES_VIEW:                    "Cookies (enter: edit value, del: delete)",
+	REDIRECTS_VIEW:                  "Redirect chain",
+	DIFF_VIEW:                       "Compare responses (enter: pick two requests)",
+	RESPONSE_DIFF_VIEW:              "Response diff",
+	ASSERTIONS_VIEW:                 "Assertions, one per line (ctrl+r to save, ctrl+q to cancel)",
+	ASSERTION_RESULTS_VIEW:          "Assertion results",
+	CAPTURES_VIEW:                   "Captures, one per line (ctrl+r to save, ctrl+q to cancel)",
+	SESSION_VARIABLES_VIEW:          "Captured variables (del: clear)",
 	HELP_VIEW:                       "Help",
 }
 
@@ -155,8 +192,38 @@ var VIEW_POSITIONS = map[string]viewPosition{
 		position{0, -9999},
 		position{0, -9999},
 		position{0, -9999}},
+	WEBSOCKET_INPUT_VIEW: {
+		position{0.3, 0},
+		position{1.0, -5},
+		position{1.0, -2},
+		position{1.0, -3}},
+	GRAPHQL_VARIABLES_VIEW: {
+		position{0.0, 0},
+		position{0.6, 1},
+		position{0.3, 0},
+		position{0.8, 1}},
 }
 
+// positions of the query and headers views in GraphQL mode
+var GRAPHQL_QUERY_POSITION = viewPosition{
+	position{0.0, 0},
+	position{0.25, 2},
+	position{0.3, 0},
+	position{0.6, 1}}
+
+var GRAPHQL_HEADERS_POSITION = viewPosition{
+	position{0.0, 0},
+	position{0.8, 1},
+	position{0.3, 0},
+	position{1.0, -3}}
+
+// position of the response body view while a websocket session is open
+var WEBSOCKET_LOG_POSITION = viewPosition{
+	position{0.3, 0},
+	position{0.25, 2},
+	position{1.0, -2},
+	position{1.0, -5}}
+
 type viewProperties struct {
 	title    string
 	frame    bool
@@ -256,6 +323,20 @@ var VIEW_PROPERTIES = map[string]viewProperties{
 		wrap:     false,
 		editor:   nil,
 	},
+	WEBSOCKET_INPUT_VIEW: {
+		title:    "Websocket message (enter: send, ctrl+b: text/binary, ctrl+p: ping)",
+		frame:    true,
+		editable: true,
+		wrap:     false,
+		editor:   &singleLineEditor{&defaultEditor},
+	},
+	GRAPHQL_VARIABLES_VIEW: {
+		title:    "GraphQL variables (JSON)",
+		frame:    true,
+		editable: true,
+		wrap:     false,
+		editor:   &defaultEditor,
+	},
 }
 
 var METHODS = []string{
@@ -272,7 +353,7 @@ var METHODS = []string{
 
 var EXPORT_FORMATS = []struct {
 	name   string
-	export func(r Request) []byte
+	export func(a *App, r Request) []byte
 }{
 	{
 		name:   "JSON",
@@ -282,15 +363,21 @@ var EXPORT_FORMATS = []struct {
 		name:   "curl",
 		export: exportCurl,
 	},
+	{
+		name:   "HAR (selected entry)",
+		export: exportHARSelected,
+	},
+	{
+		name:   "HAR (history)",
+		export: exportHARHistory,
+	},
 }
 
 const DEFAULT_METHOD = http.MethodGet
 
 var DEFAULT_FORMATTER = &formatter.TextFormatter{}
 
-var CLIENT = &http.Client{
-	Timeout: time.Duration(TIMEOUT_DURATION * time.Second),
-}
+var CLIENT = &http.Client{}
 var TRANSPORT = &http.Transport{
 	Proxy: http.ProxyFromEnvironment,
 }
@@ -307,10 +394,10 @@ var VIEWS = []string{
 }
 
 var TLS_VERSIONS = map[string]uint16{
-	"SSL3.0": tls.VersionSSL30,
 	"TLS1.0": tls.VersionTLS10,
 	"TLS1.1": tls.VersionTLS11,
 	"TLS1.2": tls.VersionTLS12,
+	"TLS1.3": tls.VersionTLS13,
 }
 
 var defaultEditor ViewEditor
@@ -321,25 +408,67 @@ const (
 )
 
 type Request struct {
-	Url             string
-	Method          string
-	GetParams       string
-	Data            string
-	Headers         string
-	ResponseHeaders string
-	RawResponseBody []byte
-	ContentType     string
-	Duration        time.Duration
-	Formatter       formatter.ResponseFormatter
+	Url                  string
+	Method               string
+	GetParams            string
+	Data                 string
+	Headers              string
+	StatusCode           int
+	Proto                string
+	ResponseHeaders      string
+	RawResponseHeaders   http.Header
+	RawResponseBody      []byte
+	ContentType          string
+	Duration             time.Duration
+	Timing               RequestTiming
+	TLS                  *TLSInfo
+	Events               []formatter.SSEEvent `json:",omitempty"`
+	Messages             []*WebSocketMessage  `json:",omitempty"`
+	GraphQL              bool                 `json:",omitempty"`
+	Variables            string               `json:",omitempty"`
+	OperationName        string               `json:",omitempty"`
+	Auth                 string               `json:",omitempty"`
+	Signing              string               `json:",omitempty"`
+	SentMethod           string               `json:",omitempty"`
+	SentURL              string               `json:",omitempty"`
+	SentHeaders          http.Header          `json:",omitempty"`
+	Redirects            []RedirectHop        `json:",omitempty"`
+	RedirectLimitReached bool                 `json:",omitempty"`
+	Assertions           string               `json:",omitempty"`
+	AssertionResults     []AssertionResult    `json:",omitempty"`
+	Captures             string               `json:",omitempty"`
+	CaptureResults       []CaptureResult      `json:",omitempty"`
+	Timestamp            time.Time
+	Formatter            formatter.ResponseFormatter `json:"-"`
+	cancel               context.CancelCauseFunc
 }
 
 type App struct {
-	viewIndex    int
-	historyIndex int
-	currentPopup string
-	history      []*Request
-	config       *config.Config
-	statusLine   *StatusLine
+	viewIndex         int
+	historyIndex      int
+	currentPopup      string
+	currentCollection string
+	collectionTree    []collectionEntry
+	collapsedFolders  map[string]bool
+	history           []*Request
+	config            *config.Config
+	configDir         string
+	statusLine        *StatusLine
+	activeRequest     *Request
+	webSocket         *webSocketSession
+	graphQL           *graphQLMode
+	grpc              grpcDescriptors
+	grpcMethodList    []string
+	selectionList     []string
+	diffBase          int
+	assertions        string
+	captures          string
+	sessionVariables  sessionVariables
+	historyStore      historyStore
+	oauth2            oauth2Tokens
+	cookies           *cookieSession
+	cookieImports     []string
+	cookieList        []*sessionCookie
 }
 
 type ViewEditor struct {
@@ -400,7 +529,7 @@ func (e *ViewEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modif
 	e.origEditor.Edit(v, key, ch, mod)
 }
 
-var symbolPattern = regexp.MustCompile("[a-zA-Z0-9-]+$")
+var symbolPattern = regexp.MustCompile("[a-zA-Z0-9_-]+$")
 
 func getLastSymbol(str string) string {
 	return symbolPattern.FindString(str)
@@ -542,8 +671,11 @@ func (p position) getCoordinate(max int) int {
 }
 
 func setView(g *gocui.Gui, viewName string) (*gocui.View, error) {
+	return setViewAt(g, viewName, VIEW_POSITIONS[viewName])
+}
+
+func setViewAt(g *gocui.Gui, viewName string, position viewPosition) (*gocui.View, error) {
 	maxX, maxY := g.Size()
-	position := VIEW_POSITIONS[viewName]
 	return g.SetView(viewName,
 		position.x0.getCoordinate(maxX+1),
 		position.y0.getCoordinate(maxY+1),
@@ -613,6 +745,12 @@ func (a *App) Layout(g *gocui.Gui) error {
 			setViewProperties(v, name)
 		}
 	}
+	if err := a.layoutGraphQL(g); err != nil {
+		return err
+	}
+	if err := a.layoutWebSocket(g); err != nil {
+		return err
+	}
 	refreshStatusLine(a, g)
 
 	return nil
@@ -725,6 +863,67 @@ func writeSortedHeaders(output io.Writer, h http.Header) {
 	}
 }
 
+func formatResponseHeaders(proto string, statusCode int, header, trailer http.Header) string {
+	// print status code
+	status_color := 32
+	if statusCode != 200 {
+		status_color = 31
+	}
+	output := &strings.Builder{}
+	fmt.Fprintf(
+		output,
+		"\x1b[0;%dm%v %v %v\x1b[0;0m\n",
+		status_color,
+		proto,
+		statusCode,
+		http.StatusText(statusCode),
+	)
+
+	writeSortedHeaders(output, header)
+
+	// According to the Go documentation, the Trailer maps trailer
+	// keys to values in the same format as Header
+	writeSortedHeaders(output, trailer)
+
+	return output.String()
+}
+
+// formatResponse formats the status line, the headers and the trailers of a
+// response and the status of gRPC calls. The trailers are received after
+// the body.
+func formatResponse(response *http.Response) string {
+	headers := formatResponseHeaders(response.Proto, response.StatusCode, response.Header, response.Trailer)
+	if isGRPCResponse(response) {
+		headers += formatGRPCStatus(response)
+	}
+	return headers
+}
+
+// responseFormatter creates the formatter of a response. The received
+// events of event streams are restored, websocket sessions are displayed
+// as a message log.
+func (a *App) responseFormatter(r *Request) formatter.ResponseFormatter {
+	if r.StatusCode == http.StatusSwitchingProtocols {
+		return &webSocketLog{a.config, r}
+	}
+	var f formatter.ResponseFormatter
+	if r.GraphQL {
+		f = formatter.NewGraphQL(a.config, r.ContentType)
+	} else {
+		f = formatter.New(a.config, r.ContentType)
+	}
+	if sse, ok := f.(*formatter.SSEFormatter); ok && len(r.Events) > 0 {
+		sse.Restore(r.Events, r.RawResponseBody)
+	}
+	return f
+}
+
+// requestBuilder builds requests with the variables of the environment
+// active at the time of the call
+func (a *App) requestBuilder() request.Builder {
+	return request.Builder{Substitute: a.variableSubstitution()}
+}
+
 func (a *App) SubmitRequest(g *gocui.Gui, _ *gocui.View) error {
 	vrb, _ := g.View(RESPONSE_BODY_VIEW)
 	vrb.Clear()
@@ -732,138 +931,186 @@ func (a *App) SubmitRequest(g *gocui.Gui, _ *gocui.View) error {
 	vrh.Clear()
 	popup(g, "Sending request..")
 
-	var r *Request = &Request{}
+	// only one request is in flight at a time
+	a.CancelRequest(g, nil)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	var r *Request = &Request{cancel: cancel}
+	a.activeRequest = r
+	// the operation of a GraphQL request is selected by the cursor
+	graphQL := a.graphQL != nil
+	graphQLLine := viewCursorLine(g, REQUEST_DATA_VIEW)
+	r.Auth = a.config.General.Auth
+	r.Signing = a.config.General.Signing
+	r.Assertions = a.assertions
+	r.Captures = a.captures
+	// the variables of the active environment, the auth and the signing
+	// are resolved before the request is sent in the background
+	builder := a.requestBuilder()
+	auth, authErr := a.requestAuth(r.Auth)
+	signer, signingErr := a.requestSigner(r.Signing)
 
 	go func(g *gocui.Gui, a *App, r *Request) error {
 		defer g.DeleteView(POPUP_VIEW)
+		defer g.Update(func(g *gocui.Gui) error {
+			if a.activeRequest == r {
+				a.activeRequest = nil
+			}
+			return nil
+		})
+		defer cancel(nil)
+		model := &request.Request{
+			Method:  getViewValue(g, REQUEST_METHOD_VIEW),
+			URL:     getViewValue(g, URL_VIEW),
+			Params:  getViewValue(g, URL_PARAMS_VIEW),
+			Headers: getViewValue(g, REQUEST_HEADERS_VIEW),
+		}
+
 		// parse url
-		r.Url = getViewValue(g, URL_VIEW)
-		u, err := url.Parse(r.Url)
+		r.Url = model.URL
+		u, err := builder.URL(model)
 		if err != nil {
 			g.Update(func(g *gocui.Gui) error {
 				vrb, _ := g.View(RESPONSE_BODY_VIEW)
-				fmt.Fprintf(vrb, "URL parse error: %v", err)
+				fmt.Fprint(vrb, err)
 				return nil
 			})
 			return nil
 		}
+		// the variables are substituted when the request is sent again
+		r.GetParams = model.Params
+
+		// parse method
+		r.Method = model.Method
 
-		q, err := url.ParseQuery(strings.Replace(getViewValue(g, URL_PARAMS_VIEW), "\n", "&", -1))
+		// set headers
+		r.Headers = model.Headers
+		headers, err := builder.Header(model)
 		if err != nil {
 			g.Update(func(g *gocui.Gui) error {
 				vrb, _ := g.View(RESPONSE_BODY_VIEW)
-				fmt.Fprintf(vrb, "Invalid GET parameters: %v", err)
+				fmt.Fprint(vrb, err)
 				return nil
 			})
 			return nil
 		}
-		originalQuery := u.Query()
-		for k, v := range q {
-			for _, qp := range v {
-				originalQuery.Add(k, qp)
-			}
-		}
-		u.RawQuery = originalQuery.Encode()
-		r.GetParams = u.RawQuery
 
-		// parse method
-		r.Method = getViewValue(g, REQUEST_METHOD_VIEW)
+		var call *grpcCall
+		grpc := u.Scheme == "grpc" || u.Scheme == "grpcs"
 
-		// set headers
-		headers := http.Header{}
-		headers.Set("User-Agent", "")
-		r.Headers = getViewValue(g, REQUEST_HEADERS_VIEW)
-		for _, header := range strings.Split(r.Headers, "\n") {
-			if header != "" {
-				header_parts := strings.SplitN(header, ": ", 2)
-				if len(header_parts) != 2 {
-					g.Update(func(g *gocui.Gui) error {
-						vrb, _ := g.View(RESPONSE_BODY_VIEW)
-						fmt.Fprintf(vrb, "Invalid header: %v", header)
-						return nil
-					})
+		// parse gRPC messages or POST/PUT/PATCH data
+		if grpc {
+			// gRPC calls are always POST requests
+			r.Method = http.MethodPost
+			r.Data = getViewValue(g, REQUEST_DATA_VIEW)
+			call, model.Body, err = a.newGRPCCall(ctx, u, headers, builder.Substitute(r.Data))
+			if err != nil {
+				g.Update(func(g *gocui.Gui) error {
+					vrb, _ := g.View(RESPONSE_BODY_VIEW)
+					fmt.Fprintf(vrb, "gRPC error: %v", err)
 					return nil
-				}
-				headers.Set(header_parts[0], header_parts[1])
+				})
+				return nil
 			}
-		}
-
-		var body io.Reader
+		} else if graphQL {
+			r.Method = http.MethodPost
+			r.GraphQL = true
+			r.Data = getViewValue(g, REQUEST_DATA_VIEW)
+			r.Variables = getViewValue(g, GRAPHQL_VARIABLES_VIEW)
+			r.OperationName = graphQLOperationName(r.Data, graphQLLine)
+			data, err := graphQLBody(builder.Substitute(r.Data), builder.Substitute(r.Variables), r.OperationName)
+			if err != nil {
+				g.Update(func(g *gocui.Gui) error {
+					vrb, _ := g.View(RESPONSE_BODY_VIEW)
+					fmt.Fprintf(vrb, "GraphQL error: %v", err)
+					return nil
+				})
+				return nil
+			}
+			if headers.Get("Content-Type") == "" {
+				headers.Set("Content-Type", config.ContentTypes["json"])
+				model.Headers += "\nContent-Type: " + config.ContentTypes["json"]
+			}
+			model.Body = data
 
-		// parse POST/PUT/PATCH data
-		if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
-			bodyStr := getViewValue(g, REQUEST_DATA_VIEW)
-			r.Data = bodyStr
-			if headers.Get("Content-Type") != "multipart/form-data" {
-				if headers.Get("Content-Type") == "application/x-www-form-urlencoded" {
-					bodyStr = strings.Replace(bodyStr, "\n", "&", -1)
-				}
-				body = bytes.NewBufferString(bodyStr)
-			} else {
-				var bodyBytes bytes.Buffer
-				multiWriter := multipart.NewWriter(&bodyBytes)
-				defer multiWriter.Close()
-				postData, err := url.ParseQuery(strings.Replace(getViewValue(g, REQUEST_DATA_VIEW), "\n", "&", -1))
-				if err != nil {
-					return err
-				}
-				for postKey, postValues := range postData {
-					for i := range postValues {
-						if len([]rune(postValues[i])) > 0 && postValues[i][0] == '@' {
-							file, err := os.Open(postValues[i][1:])
-							if err != nil {
-								g.Update(func(g *gocui.Gui) error {
-									vrb, _ := g.View(RESPONSE_BODY_VIEW)
-									fmt.Fprintf(vrb, "Error: %v", err)
-									return nil
-								})
-								return err
-							}
-							defer file.Close()
-							fw, err := multiWriter.CreateFormFile(postKey, path.Base(postValues[i][1:]))
-							if err != nil {
-								return err
-							}
-							if _, err := io.Copy(fw, file); err != nil {
-								return err
-							}
-						} else {
-							fw, err := multiWriter.CreateFormField(postKey)
-							if err != nil {
-								return err
-							}
-							if _, err := fw.Write([]byte(postValues[i])); err != nil {
-								return err
-							}
-						}
-					}
+			// the schema is fetched again if the endpoint changed
+			endpoint, schemaHeaders := u.String(), model.Headers
+			g.UpdateAsync(func(g *gocui.Gui) error {
+				if a.graphQL != nil && a.graphQL.schemaURL != endpoint {
+					a.fetchGraphQLSchema(g, endpoint, schemaHeaders)
 				}
-				body = bytes.NewReader(bodyBytes.Bytes())
-			}
+				return nil
+			})
+		} else if request.HasBody(r.Method) {
+			r.Data = getViewValue(g, REQUEST_DATA_VIEW)
+			model.Data = r.Data
+		}
+		model.Method = r.Method
+
+		if authErr != nil {
+			g.Update(func(g *gocui.Gui) error {
+				vrb, _ := g.View(RESPONSE_BODY_VIEW)
+				fmt.Fprintf(vrb, "Auth error: %v", authErr)
+				return nil
+			})
+			return nil
+		}
+		if signingErr != nil {
+			g.Update(func(g *gocui.Gui) error {
+				vrb, _ := g.View(RESPONSE_BODY_VIEW)
+				fmt.Fprintf(vrb, "Signing error: %v", signingErr)
+				return nil
+			})
+			return nil
 		}
 
 		// create request
-		req, err := http.NewRequest(r.Method, u.String(), body)
+		req, err := builder.Build(ctx, model)
 		if err != nil {
 			g.Update(func(g *gocui.Gui) error {
 				vrb, _ := g.View(RESPONSE_BODY_VIEW)
-				fmt.Fprintf(vrb, "Request error: %v", err)
+				fmt.Fprintf(vrb, "Error: %v", err)
 				return nil
 			})
 			return nil
 		}
-		req.Header = headers
+		if grpc {
+			setGRPCRequest(req)
+		}
 
-		// set the `Host` header
-		if headers.Get("Host") != "" {
-			req.Host = headers.Get("Host")
+		var webSocketAccept string
+		if u.Scheme == "ws" || u.Scheme == "wss" {
+			webSocketAccept, err = webSocketHandshake(req)
+			if err != nil {
+				g.Update(func(g *gocui.Gui) error {
+					vrb, _ := g.View(RESPONSE_BODY_VIEW)
+					fmt.Fprintf(vrb, "Websocket error: %v", err)
+					return nil
+				})
+				return nil
+			}
 		}
 
 		// do request
 		start := time.Now()
-		response, err := CLIENT.Do(req)
+		r.Timestamp = start
+		tracer := newRequestTracer(start)
+		requestCtx, redirects := withRedirectChain(withClientCertificateTarget(ctx))
+		req = req.WithContext(httptrace.WithClientTrace(requestCtx, tracer.ClientTrace()))
+		stopTimeout := a.responseTimeout(cancel)
+		client := CLIENT
+		if call != nil && !call.web {
+			client = GRPC_CLIENT
+		} else if webSocketAccept != "" {
+			client = WEBSOCKET_CLIENT
+		}
+		response, err := a.sendWithAuth(client, req, auth, signer)
+		stopTimeout()
+		// the duration is measured again when the body is read
 		r.Duration = time.Since(start)
 		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				err = cause
+			}
 			g.Update(func(g *gocui.Gui) error {
 				vrb, _ := g.View(RESPONSE_BODY_VIEW)
 				fmt.Fprintf(vrb, "Response error: %v", err)
@@ -872,70 +1119,80 @@ func (a *App) SubmitRequest(g *gocui.Gui, _ *gocui.View) error {
 			return nil
 		}
 		defer response.Body.Close()
-
-		// extract body
-		r.ContentType = response.Header.Get("Content-Type")
-		if response.Header.Get("Content-Encoding") == "gzip" {
-			reader, err := gzip.NewReader(response.Body)
-			if err == nil {
-				defer reader.Close()
-				response.Body = reader
-			} else {
+		// the headers of the last request, set by auth, signing and
+		// redirects
+		r.SentMethod = response.Request.Method
+		r.SentURL = response.Request.URL.String()
+		r.SentHeaders = response.Request.Header.Clone()
+		r.Redirects = redirects.hops
+		r.RedirectLimitReached = redirects.limitReached
+		r.Timing = tracer.Timing()
+
+		grpcResponse := call != nil && isGRPCResponse(response)
+		if grpcResponse {
+			// the status of a call is sent in the trailers after the
+			// messages, the decoded messages are displayed as JSON
+			decoded, err := call.decodeResponse(response)
+			if err != nil {
+				if cause := context.Cause(ctx); cause != nil {
+					err = cause
+				}
 				g.Update(func(g *gocui.Gui) error {
 					vrb, _ := g.View(RESPONSE_BODY_VIEW)
-					fmt.Fprintf(vrb, "Cannot uncompress response: %v", err)
+					fmt.Fprintf(vrb, "gRPC error: %v", err)
 					return nil
 				})
 				return nil
 			}
+			response.Body = io.NopCloser(bytes.NewReader(decoded))
 		}
 
-		bodyBytes, err := ioutil.ReadAll(response.Body)
-		if err == nil {
-			r.RawResponseBody = bodyBytes
+		// extract body
+		r.ContentType = response.Header.Get("Content-Type")
+		if grpcResponse {
+			r.ContentType = config.ContentTypes["json"]
 		}
+		request.Uncompress(response)
 
-		r.Formatter = formatter.New(a.config, r.ContentType)
-
-		// add to history
-		a.history = append(a.history, r)
-		a.historyIndex = len(a.history) - 1
-
-		// render response
-		g.Update(func(g *gocui.Gui) error {
-			vrh, _ := g.View(RESPONSE_HEADERS_VIEW)
+		r.StatusCode = response.StatusCode
+		r.Formatter = a.responseFormatter(r)
+		r.RawResponseBody = []byte{}
 
-			a.PrintBody(g)
+		r.Proto = response.Proto
+		r.RawResponseHeaders = response.Header
+		r.TLS = newTLSInfo(response.TLS)
+		r.ResponseHeaders = formatResponse(response)
 
-			// print status code
-			status_color := 32
-			if response.StatusCode != 200 {
-				status_color = 31
-			}
-			header := &strings.Builder{}
-			fmt.Fprintf(
-				header,
-				"\x1b[0;%dmHTTP/1.1 %v %v\x1b[0;0m\n",
-				status_color,
-				response.StatusCode,
-				http.StatusText(response.StatusCode),
-			)
-
-			writeSortedHeaders(header, response.Header)
-
-			// According to the Go documentation, the Trailer maps trailer
-			// keys to values in the same format as Header
-			writeSortedHeaders(header, response.Trailer)
-
-			r.ResponseHeaders = header.String()
+		// add to history and render the headers, the body is rendered
+		// while it is received
+		g.UpdateAsync(func(g *gocui.Gui) error {
+			g.DeleteView(POPUP_VIEW)
+			a.history = append(a.history, r)
+			a.historyIndex = len(a.history) - 1
 
+			vrh, _ := g.View(RESPONSE_HEADERS_VIEW)
+			vrh.Clear()
 			fmt.Fprint(vrh, r.ResponseHeaders)
 			if _, err := vrh.Line(0); err != nil {
 				vrh.SetOrigin(0, 0)
 			}
 
+			vrb, _ := g.View(RESPONSE_BODY_VIEW)
+			vrb.Clear()
+			vrb.Title = VIEW_PROPERTIES[vrb.Name()].title + " " + r.Formatter.Title()
+			vrb.SetOrigin(0, 0)
 			return nil
 		})
+		if webSocketAccept != "" && response.StatusCode == http.StatusSwitchingProtocols {
+			a.completeResponseBody(g, r, response, a.runWebSocket(g, ctx, r, response, webSocketAccept))
+			return nil
+		}
+		if _, isEventStream := r.Formatter.(*formatter.SSEFormatter); isEventStream {
+			client := &request.Client{Builder: builder, HTTPClient: authClient{a, CLIENT, auth, signer}}
+			a.streamEvents(g, ctx, r, client, model, response, tracer)
+			return nil
+		}
+		a.streamResponseBody(g, ctx, r, response, tracer)
 		return nil
 	}(g, a, r)
 
@@ -943,54 +1200,62 @@ func (a *App) SubmitRequest(g *gocui.Gui, _ *gocui.View) error {
 }
 
 func (a *App) PrintBody(g *gocui.Gui) {
-	g.Update(func(g *gocui.Gui) error {
-		if len(a.history) == 0 {
-			return nil
-		}
-		req := a.history[a.historyIndex]
-		if req.RawResponseBody == nil {
-			return nil
-		}
-		vrb, _ := g.View(RESPONSE_BODY_VIEW)
-		vrb.Clear()
+	g.Update(a.printBody)
+}
 
-		var responseFormatter formatter.ResponseFormatter
-		responseFormatter = req.Formatter
+func (a *App) printBody(g *gocui.Gui) error {
+	if len(a.history) == 0 {
+		return nil
+	}
+	req := a.history[a.historyIndex]
+	if req.RawResponseBody == nil {
+		return nil
+	}
+	vrb, _ := g.View(RESPONSE_BODY_VIEW)
+	vrb.Clear()
 
-		vrb.Title = VIEW_PROPERTIES[vrb.Name()].title + " " + responseFormatter.Title()
+	var responseFormatter formatter.ResponseFormatter
+	responseFormatter = req.Formatter
 
-		search_text := getViewValue(g, "search")
-		if search_text == "" || !responseFormatter.Searchable() {
-			err := responseFormatter.Format(vrb, req.RawResponseBody)
-			if err != nil {
-				fmt.Fprintf(vrb, "Error: cannot decode response body: %v", err)
-				return nil
-			}
-			if _, err := vrb.Line(0); !a.config.General.PreserveScrollPosition || err != nil {
-				vrb.SetOrigin(0, 0)
-			}
-			return nil
-		}
-		if !a.config.General.ContextSpecificSearch {
-			responseFormatter = DEFAULT_FORMATTER
-		}
-		vrb.SetOrigin(0, 0)
-		results, err := responseFormatter.Search(search_text, req.RawResponseBody)
+	vrb.Title = VIEW_PROPERTIES[vrb.Name()].title + " " + responseFormatter.Title()
+
+	search_text := getViewValue(g, "search")
+	if search_text == "" || !responseFormatter.Searchable() {
+		err := responseFormatter.Format(vrb, req.RawResponseBody)
 		if err != nil {
-			fmt.Fprint(vrb, "Search error: ", err)
+			fmt.Fprintf(vrb, "Error: cannot decode response body: %v", err)
 			return nil
 		}
-		if len(results) == 0 {
-			vrb.Title = "No results"
-			fmt.Fprint(vrb, "Error: no results")
-			return nil
+		if _, err := vrb.Line(0); !a.config.General.PreserveScrollPosition || err != nil {
+			vrb.SetOrigin(0, 0)
 		}
-		vrb.Title = fmt.Sprintf("%d results", len(results))
-		for _, result := range results {
-			fmt.Fprintf(vrb, "-----\n%s\n", result)
+		return nil
+	}
+	// events of event streams and websocket messages are always filtered
+	// with regular expressions
+	switch responseFormatter.(type) {
+	case *formatter.SSEFormatter, *webSocketLog:
+	default:
+		if !a.config.General.ContextSpecificSearch {
+			responseFormatter = DEFAULT_FORMATTER
 		}
+	}
+	vrb.SetOrigin(0, 0)
+	results, err := responseFormatter.Search(search_text, req.RawResponseBody)
+	if err != nil {
+		fmt.Fprint(vrb, "Search error: ", err)
 		return nil
-	})
+	}
+	if len(results) == 0 {
+		vrb.Title = "No results"
+		fmt.Fprint(vrb, "Error: no results")
+		return nil
+	}
+	vrb.Title = fmt.Sprintf("%d results", len(results))
+	for _, result := range results {
+		fmt.Fprintf(vrb, "-----\n%s\n", result)
+	}
+	return nil
 }
 
 func parseKey(k string) (interface{}, gocui.Modifier, error) {
@@ -1132,15 +1397,33 @@ func (a *App) SetKeys(g *gocui.Gui) error {
 	g.SetKeybinding(HISTORY_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
 	g.SetKeybinding(HISTORY_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
 	g.SetKeybinding(HISTORY_VIEW, gocui.KeyEnter, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
-		_, cy := v.Cursor()
+		idx := viewCursorLine(g, HISTORY_VIEW)
 		// TODO error
-		if len(a.history) <= cy {
+		if len(a.history) <= idx {
 			return nil
 		}
-		a.restoreRequest(g, cy, false)
+		a.restoreRequest(g, idx, false)
 		return nil
 	})
 
+	g.SetKeybinding(HISTORY_VIEW, 'r', gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		idx := viewCursorLine(g, HISTORY_VIEW)
+		if len(a.history) <= idx {
+			return nil
+		}
+		a.restoreRequest(g, idx, false)
+		return a.SubmitRequest(g, v)
+	})
+
+	// collection key bindings
+	g.SetKeybinding(COLLECTIONS_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
+	g.SetKeybinding(COLLECTIONS_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
+	g.SetKeybinding(COLLECTIONS_VIEW, gocui.KeyEnter, gocui.ModNone, a.openCollectionEntry)
+	g.SetKeybinding(COLLECTIONS_VIEW, 'n', gocui.ModNone, a.newCollectionFolder)
+	g.SetKeybinding(COLLECTIONS_VIEW, 'r', gocui.ModNone, a.renameCollectionEntry)
+	g.SetKeybinding(COLLECTIONS_VIEW, 'd', gocui.ModNone, a.duplicateCollectionEntry)
+	g.SetKeybinding(COLLECTIONS_VIEW, gocui.KeyDelete, gocui.ModNone, a.deleteCollectionEntry)
+
 	// method key bindings
 	g.SetKeybinding(REQUEST_METHOD_VIEW, gocui.KeyArrowDown, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
 		value := strings.TrimSpace(v.Buffer())
@@ -1170,6 +1453,22 @@ func (a *App) SetKeys(g *gocui.Gui) error {
 		a.closePopup(g, METHOD_LIST_VIEW)
 		return nil
 	})
+	g.SetKeybinding(GRPC_METHODS_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
+	g.SetKeybinding(GRPC_METHODS_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
+	g.SetKeybinding(GRPC_METHODS_VIEW, gocui.KeyEnter, gocui.ModNone, a.selectGRPCMethod)
+	g.SetKeybinding(AUTH_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
+	g.SetKeybinding(AUTH_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
+	g.SetKeybinding(AUTH_VIEW, gocui.KeyEnter, gocui.ModNone, a.selectAuth)
+	g.SetKeybinding(SIGNING_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
+	g.SetKeybinding(SIGNING_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
+	g.SetKeybinding(SIGNING_VIEW, gocui.KeyEnter, gocui.ModNone, a.selectSigning)
+	g.SetKeybinding(COOKIES_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
+	g.SetKeybinding(COOKIES_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
+	g.SetKeybinding(COOKIES_VIEW, gocui.KeyEnter, gocui.ModNone, a.editCookie)
+	g.SetKeybinding(COOKIES_VIEW, gocui.KeyDelete, gocui.ModNone, a.deleteCookie)
+	g.SetKeybinding(DIFF_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
+	g.SetKeybinding(DIFF_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
+	g.SetKeybinding(DIFF_VIEW, gocui.KeyEnter, gocui.ModNone, a.selectDiffEntry)
 	g.SetKeybinding(SAVE_REQUEST_FORMAT_DIALOG_VIEW, gocui.KeyArrowDown, gocui.ModNone, cursDown)
 	g.SetKeybinding(SAVE_REQUEST_FORMAT_DIALOG_VIEW, gocui.KeyArrowUp, gocui.ModNone, cursUp)
 
@@ -1178,6 +1477,34 @@ func (a *App) SetKeys(g *gocui.Gui) error {
 		return nil
 	})
 
+	g.SetKeybinding(ASSERTIONS_VIEW, gocui.KeyCtrlQ, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		a.closePopup(g, ASSERTIONS_VIEW)
+		return nil
+	})
+	g.SetKeybinding(ASSERTIONS_VIEW, gocui.KeyCtrlR, gocui.ModNone, a.saveAssertions)
+
+	g.SetKeybinding(CAPTURES_VIEW, gocui.KeyCtrlQ, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		a.closePopup(g, CAPTURES_VIEW)
+		return nil
+	})
+	g.SetKeybinding(CAPTURES_VIEW, gocui.KeyCtrlR, gocui.ModNone, a.saveCaptures)
+	g.SetKeybinding(SESSION_VARIABLES_VIEW, gocui.KeyDelete, gocui.ModNone, a.clearSessionVariables)
+
+	g.SetKeybinding(IMPORT_CURL_DIALOG_VIEW, gocui.KeyCtrlQ, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		a.closePopup(g, IMPORT_CURL_DIALOG_VIEW)
+		return nil
+	})
+	g.SetKeybinding(IMPORT_CURL_DIALOG_VIEW, gocui.KeyCtrlR, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		command := v.Buffer()
+		a.closePopup(g, IMPORT_CURL_DIALOG_VIEW)
+		if err := a.ImportCurl(g, command); err != nil {
+			vrb, _ := g.View(RESPONSE_BODY_VIEW)
+			vrb.Clear()
+			fmt.Fprintf(vrb, "Curl import error: %v", err)
+		}
+		return nil
+	})
+
 	g.SetKeybinding(SAVE_RESULT_VIEW, gocui.KeyEnter, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
 		a.closePopup(g, SAVE_RESULT_VIEW)
 		return nil
@@ -1265,6 +1592,13 @@ func (a *App) LoadRequest(g *gocui.Gui, loadLocation string) (err error) {
 		setViewTextAndCursor(v, params)
 	}
 
+	variables, exists := requestMap[GRAPHQL_VARIABLES_VIEW]
+	a.setGraphQLMode(g, exists)
+	if exists {
+		v, _ = g.View(GRAPHQL_VARIABLES_VIEW)
+		setViewTextAndCursor(v, variables)
+	}
+
 	data, exists := requestMap[REQUEST_DATA_VIEW]
 	if exists {
 		g.Update(func(g *gocui.Gui) error {
@@ -1280,6 +1614,13 @@ func (a *App) LoadRequest(g *gocui.Gui, loadLocation string) (err error) {
 		v, _ = g.View(REQUEST_HEADERS_VIEW)
 		setViewTextAndCursor(v, headers)
 	}
+
+	// requests saved without auth or signing are sent without them
+	a.config.General.Auth = requestMap[AUTH_VIEW]
+	a.config.General.Signing = requestMap[SIGNING_VIEW]
+	a.assertions = requestMap[ASSERTIONS_VIEW]
+	a.captures = requestMap[CAPTURES_VIEW]
+	refreshStatusLine(a, g)
 	return nil
 }
 
@@ -1320,6 +1661,24 @@ func (a *App) ToggleHistory(g *gocui.Gui, _ *gocui.View) (err error) {
 	return
 }
 
+// editedRequest returns the request in the views, the GraphQL mode and the
+// selected auth, signing, assertions and captures
+func (a *App) editedRequest(g *gocui.Gui) Request {
+	return Request{
+		Url:        getViewValue(g, URL_VIEW),
+		Method:     getViewValue(g, REQUEST_METHOD_VIEW),
+		GetParams:  getViewValue(g, URL_PARAMS_VIEW),
+		Data:       getViewValue(g, REQUEST_DATA_VIEW),
+		Headers:    getViewValue(g, REQUEST_HEADERS_VIEW),
+		GraphQL:    a.graphQL != nil,
+		Variables:  getViewValue(g, GRAPHQL_VARIABLES_VIEW),
+		Auth:       a.config.General.Auth,
+		Signing:    a.config.General.Signing,
+		Assertions: a.assertions,
+		Captures:   a.captures,
+	}
+}
+
 func (a *App) SaveRequest(g *gocui.Gui, _ *gocui.View) (err error) {
 	// Destroy if present
 	if a.currentPopup == SAVE_REQUEST_FORMAT_DIALOG_VIEW {
@@ -1348,25 +1707,31 @@ func (a *App) SaveRequest(g *gocui.Gui, _ *gocui.View) (err error) {
 	g.SetKeybinding(SAVE_REQUEST_FORMAT_DIALOG_VIEW, gocui.KeyEnter, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
 		// Save the format index
 		_, format := v.Cursor()
+		// JSON requests are saved into the current collection by default
+		saveDir, err := os.Getwd()
+		if EXPORT_FORMATS[format].name == "JSON" {
+			saveDir, err = a.collectionPath(a.currentCollection), nil
+		}
+		if err != nil {
+			saveDir = ""
+		}
+		saveDir += string(filepath.Separator)
 		// Open the Save popup
-		return a.OpenSaveDialog(VIEW_TITLES[SAVE_REQUEST_DIALOG_VIEW], g,
+		return a.OpenDialog(VIEW_TITLES[SAVE_REQUEST_DIALOG_VIEW], saveDir, g,
 			func(g *gocui.Gui, _ *gocui.View) error {
 				defer a.closePopup(g, SAVE_DIALOG_VIEW)
 				saveLocation := getViewValue(g, SAVE_DIALOG_VIEW)
 
-				r := Request{
-					Url:       getViewValue(g, URL_VIEW),
-					Method:    getViewValue(g, REQUEST_METHOD_VIEW),
-					GetParams: getViewValue(g, URL_PARAMS_VIEW),
-					Data:      getViewValue(g, REQUEST_DATA_VIEW),
-					Headers:   getViewValue(g, REQUEST_HEADERS_VIEW),
-				}
+				r := a.editedRequest(g)
 
 				// Export the request using the chosent format
-				request := EXPORT_FORMATS[format].export(r)
+				request := EXPORT_FORMATS[format].export(a, r)
 
 				// Write the file
-				ioerr := ioutil.WriteFile(saveLocation, []byte(request), 0644)
+				ioerr := os.MkdirAll(filepath.Dir(saveLocation), 0755)
+				if ioerr == nil {
+					ioerr = ioutil.WriteFile(saveLocation, []byte(request), 0644)
+				}
 
 				saveResult := fmt.Sprintf("Request saved successfully in %s", EXPORT_FORMATS[format].name)
 				if ioerr != nil {
@@ -1409,6 +1774,17 @@ func (a *App) ToggleMethodList(g *gocui.Gui, _ *gocui.View) (err error) {
 }
 
 func (a *App) OpenSaveDialog(title string, g *gocui.Gui, save func(g *gocui.Gui, v *gocui.View) error) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		currentDir = ""
+	}
+	currentDir += "/"
+
+	return a.OpenDialog(title, currentDir, g, save)
+}
+
+// OpenDialog opens a single line input popup prefilled with value
+func (a *App) OpenDialog(title, value string, g *gocui.Gui, save func(g *gocui.Gui, v *gocui.View) error) error {
 	dialog, err := a.CreatePopupView(SAVE_DIALOG_VIEW, 60, 1, g)
 	if err != nil {
 		return err
@@ -1419,17 +1795,11 @@ func (a *App) OpenSaveDialog(title string, g *gocui.Gui, save func(g *gocui.Gui,
 	dialog.Editable = true
 	dialog.Wrap = false
 
-	currentDir, err := os.Getwd()
-	if err != nil {
-		currentDir = ""
-	}
-	currentDir += "/"
-
-	setViewTextAndCursor(dialog, currentDir)
+	setViewTextAndCursor(dialog, value)
 
 	g.SetViewOnTop(SAVE_DIALOG_VIEW)
 	g.SetCurrentView(SAVE_DIALOG_VIEW)
-	dialog.SetCursor(0, len(currentDir))
+	dialog.SetCursor(0, len(value))
 	g.DeleteKeybinding(SAVE_DIALOG_VIEW, gocui.KeyEnter, gocui.ModNone)
 	g.SetKeybinding(SAVE_DIALOG_VIEW, gocui.KeyEnter, gocui.ModNone, save)
 	return nil
@@ -1468,6 +1838,10 @@ func (a *App) restoreRequest(g *gocui.Gui, idx int, isCleanToggle bool) {
 		a.closePopup(g, HISTORY_VIEW)
 		a.historyIndex = idx
 		r = a.history[idx]
+		a.config.General.Auth = r.Auth
+		a.config.General.Signing = r.Signing
+		a.assertions = r.Assertions
+		a.captures = r.Captures
 	}
 
 	v, _ := g.View(URL_VIEW)
@@ -1482,6 +1856,12 @@ func (a *App) restoreRequest(g *gocui.Gui, idx int, isCleanToggle bool) {
 	v, _ = g.View(REQUEST_DATA_VIEW)
 	setViewTextAndCursor(v, r.Data)
 
+	a.setGraphQLMode(g, r.GraphQL)
+	if r.GraphQL {
+		v, _ = g.View(GRAPHQL_VARIABLES_VIEW)
+		setViewTextAndCursor(v, r.Variables)
+	}
+
 	v, _ = g.View(REQUEST_HEADERS_VIEW)
 	setViewTextAndCursor(v, r.Headers)
 
@@ -1502,6 +1882,7 @@ func (a *App) LoadConfig(configPath string) error {
 		// Load config from default path
 		configPath = config.GetDefaultConfigLocation()
 	}
+	a.configDir = filepath.Dir(configPath)
 
 	// If the config file doesn't exist, load the default config
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -1529,20 +1910,56 @@ func (a *App) LoadConfig(configPath string) error {
 	return nil
 }
 
+// ParseArgs fills the request views from command line arguments
 func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 	a.Layout(g)
 	g.SetCurrentView(VIEWS[a.viewIndex])
-	vheader, err := g.View(REQUEST_HEADERS_VIEW)
-	if err != nil {
+	if _, err := g.View(REQUEST_HEADERS_VIEW); err != nil {
 		return errors.New("Too small screen")
 	}
-	vheader.Clear()
-	vget, _ := g.View(URL_PARAMS_VIEW)
-	vget.Clear()
+	r := &Request{
+		Url:       getViewValue(g, URL_VIEW),
+		Method:    getViewValue(g, REQUEST_METHOD_VIEW),
+		Data:      getViewValue(g, REQUEST_DATA_VIEW),
+		GraphQL:   a.graphQL != nil,
+		Variables: getViewValue(g, GRAPHQL_VARIABLES_VIEW),
+	}
+	if err := a.parseArgs(r, args); err != nil {
+		return err
+	}
+
+	v, _ := g.View(URL_VIEW)
+	setViewTextAndCursor(v, r.Url)
+	v, _ = g.View(REQUEST_METHOD_VIEW)
+	setViewTextAndCursor(v, r.Method)
+	v, _ = g.View(URL_PARAMS_VIEW)
+	setViewTextAndCursor(v, r.GetParams)
+	v, _ = g.View(REQUEST_HEADERS_VIEW)
+	setViewTextAndCursor(v, r.Headers)
+	v, _ = g.View(REQUEST_DATA_VIEW)
+	setViewTextAndCursor(v, r.Data)
+	// the schema is fetched with the first request, the transport is not
+	// configured yet
+	a.setGraphQLMode(g, r.GraphQL)
+	if r.GraphQL {
+		v, _ = g.View(GRAPHQL_VARIABLES_VIEW)
+		setViewTextAndCursor(v, r.Variables)
+	}
+	refreshStatusLine(a, g)
+	return nil
+}
+
+// parseArgs applies curl style command line arguments to a request and
+// to the config. The headers and GET parameters of the request are
+// replaced.
+func (a *App) parseArgs(r *Request, args []string) error {
+	r.Headers = ""
+	r.GetParams = ""
 	content_type := ""
 	set_data := false
 	set_method := false
 	set_binary_data := false
+	set_graphql := false
 	arg_index := 1
 	args_len := len(args)
 	accept_types := make([]string, 0, 8)
@@ -1556,15 +1973,51 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			}
 			arg_index += 1
 			header := args[arg_index]
-			fmt.Fprintf(vheader, "%v\n", header)
-		case "-d", "--data", "--data-binary", "--data-urlencode":
+			// curl accepts headers without a space after the colon
+			if header_parts := strings.SplitN(header, ":", 2); len(header_parts) == 2 {
+				header = strings.TrimSpace(header_parts[0]) + ": " + strings.TrimSpace(header_parts[1])
+			}
+			r.Headers += header + "\n"
+		case "-A", "--user-agent":
+			if arg_index == args_len-1 {
+				return errors.New("No user agent specified")
+			}
+			arg_index += 1
+			r.Headers += fmt.Sprintf("User-Agent: %v\n", args[arg_index])
+		case "-b", "--cookie":
+			if arg_index == args_len-1 {
+				return errors.New("No cookie specified")
+			}
+			arg_index += 1
+			// like curl, values without "=" are cookie files
+			if !strings.Contains(args[arg_index], "=") {
+				a.config.General.CookieJar = true
+				a.cookieImports = append(a.cookieImports, args[arg_index])
+				break
+			}
+			r.Headers += fmt.Sprintf("Cookie: %v\n", args[arg_index])
+		case "-c", "--cookie-jar":
+			if arg_index == args_len-1 {
+				return errors.New("No cookie jar specified")
+			}
+			arg_index += 1
+			a.config.General.CookieJar = true
+			a.config.General.CookieFile = args[arg_index]
+		case "-u", "--user":
+			if arg_index == args_len-1 {
+				return errors.New("No user specified")
+			}
+			arg_index += 1
+			credentials := base64.StdEncoding.EncodeToString([]byte(args[arg_index]))
+			r.Headers += fmt.Sprintf("Authorization: Basic %v\n", credentials)
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw", "--data-urlencode":
 			if arg_index == args_len-1 {
 				return errors.New("No POST/PUT/PATCH value specified")
 			}
 
 			arg_index += 1
 			set_data = true
-			set_binary_data = arg == "--data-binary"
+			set_binary_data = arg == "--data-binary" || arg == "--data-raw"
 			arg_data := args[arg_index]
 
 			if !set_binary_data {
@@ -1588,8 +2041,7 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			content_type = "json"
 			accept_types = append(accept_types, config.ContentTypes["json"])
 			set_data = true
-			vdata, _ := g.View(REQUEST_DATA_VIEW)
-			setViewTextAndCursor(vdata, json_str)
+			r.Data = json_str
 		case "-X", "--request":
 			if arg_index == args_len-1 {
 				return errors.New("No HTTP method specified")
@@ -1600,8 +2052,7 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			if content_type == "" && (method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch) {
 				content_type = "form"
 			}
-			vmethod, _ := g.View(REQUEST_METHOD_VIEW)
-			setViewTextAndCursor(vmethod, method)
+			r.Method = method
 		case "-t", "--timeout":
 			if arg_index == args_len-1 {
 				return errors.New("No timeout value specified")
@@ -1613,9 +2064,8 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			}
 			a.config.General.Timeout = config.Duration{Duration: time.Duration(timeout) * time.Millisecond}
 		case "--compressed":
-			vh, _ := g.View(REQUEST_HEADERS_VIEW)
-			if !strings.Contains(getViewValue(g, REQUEST_HEADERS_VIEW), "Accept-Encoding") {
-				fmt.Fprintln(vh, "Accept-Encoding: gzip, deflate")
+			if !strings.Contains(r.Headers, "Accept-Encoding") {
+				r.Headers += "Accept-Encoding: gzip, deflate\n"
 			}
 		case "-e", "--editor":
 			if arg_index == args_len-1 {
@@ -1623,10 +2073,133 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			}
 			arg_index += 1
 			a.config.General.Editor = args[arg_index]
+		case "--env":
+			if arg_index == args_len-1 {
+				return errors.New("No environment specified")
+			}
+			arg_index += 1
+			env := args[arg_index]
+			if _, found := a.config.Environments[env]; !found {
+				return fmt.Errorf("Unknown environment: %v", env)
+			}
+			a.config.General.Environment = env
+		case "--auth":
+			if arg_index == args_len-1 {
+				return errors.New("No auth specified")
+			}
+			arg_index += 1
+			auth := args[arg_index]
+			if _, found := a.config.Auth[auth]; !found {
+				return fmt.Errorf("Unknown auth: %v", auth)
+			}
+			a.config.General.Auth = auth
+		case "--sign":
+			if arg_index == args_len-1 {
+				return errors.New("No signing specified")
+			}
+			arg_index += 1
+			signing := args[arg_index]
+			if _, found := a.config.Signing[signing]; !found {
+				return fmt.Errorf("Unknown signing: %v", signing)
+			}
+			a.config.General.Signing = signing
+		case "-E", "--cert":
+			if arg_index == args_len-1 {
+				return errors.New("No client certificate specified")
+			}
+			arg_index += 1
+			// curl style certificate[:passphrase] value
+			cert := args[arg_index]
+			if i := strings.LastIndex(cert, ":"); i > 1 {
+				a.config.General.ClientKeyPassphrase = cert[i+1:]
+				cert = cert[:i]
+			}
+			a.config.General.ClientCert = cert
+		case "--key":
+			if arg_index == args_len-1 {
+				return errors.New("No client key specified")
+			}
+			arg_index += 1
+			a.config.General.ClientKey = args[arg_index]
+		case "--pass":
+			if arg_index == args_len-1 {
+				return errors.New("No passphrase specified")
+			}
+			arg_index += 1
+			a.config.General.ClientKeyPassphrase = args[arg_index]
+		case "--cacert":
+			if arg_index == args_len-1 {
+				return errors.New("No CA certificate specified")
+			}
+			arg_index += 1
+			a.config.General.CACert = args[arg_index]
+		case "--proto":
+			if arg_index == args_len-1 {
+				return errors.New("No .proto file specified")
+			}
+			arg_index += 1
+			a.config.General.ProtoFiles = append(a.config.General.ProtoFiles, args[arg_index])
+		case "--import-path":
+			if arg_index == args_len-1 {
+				return errors.New("No import path specified")
+			}
+			arg_index += 1
+			a.config.General.ProtoImportPaths = append(a.config.General.ProtoImportPaths, args[arg_index])
 		case "-k", "--insecure":
 			a.config.General.Insecure = true
 		case "-R", "--disable-redirects":
 			a.config.General.FollowRedirects = false
+		case "-L", "--location":
+			a.config.General.FollowRedirects = true
+		case "--max-redirs":
+			if arg_index == args_len-1 {
+				return errors.New("No maximum redirect count specified")
+			}
+			arg_index += 1
+			max, err := strconv.Atoi(args[arg_index])
+			if err != nil {
+				return fmt.Errorf("Invalid maximum redirect count: %v", args[arg_index])
+			}
+			a.config.General.MaxRedirects = max
+		case "--http1.1":
+			a.config.General.HTTPVersion = HTTP_VERSION_1_1
+		case "--http2":
+			a.config.General.HTTPVersion = HTTP_VERSION_2
+		case "--http2-prior-knowledge":
+			a.config.General.HTTPVersion = HTTP_VERSION_2_PRIOR_KNOWLEDGE
+		case "--unix-socket":
+			if arg_index == args_len-1 {
+				return errors.New("No unix socket specified")
+			}
+			arg_index += 1
+			a.config.General.UnixSocket = args[arg_index]
+		case "--resolve":
+			if arg_index == args_len-1 {
+				return errors.New("No resolve value specified")
+			}
+			arg_index += 1
+			if _, _, err := parseResolve(args[arg_index]); err != nil {
+				return err
+			}
+			a.config.General.Resolve = append(a.config.General.Resolve, args[arg_index])
+		case "--connect-to":
+			if arg_index == args_len-1 {
+				return errors.New("No connect-to value specified")
+			}
+			arg_index += 1
+			if _, err := parseConnectTo(args[arg_index]); err != nil {
+				return err
+			}
+			a.config.General.ConnectTo = append(a.config.General.ConnectTo, args[arg_index])
+		case "--graphql":
+			set_graphql = true
+		case "-I", "--head":
+			set_method = true
+			r.Method = http.MethodHead
+		case "-s", "--silent", "-S", "--show-error", "-v", "--verbose", "-i", "--include", "-g", "--globoff", "-N", "--no-buffer":
+			// curl output options, ignored
+		case "--batch", "--print", "--raw":
+			// output options of the batch mode, see runBatch
 		case "--tlsv1.0":
 			a.config.General.TLSVersionMin = tls.VersionTLS10
 			a.config.General.TLSVersionMax = tls.VersionTLS10
@@ -1636,9 +2209,30 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 		case "--tlsv1.2":
 			a.config.General.TLSVersionMin = tls.VersionTLS12
 			a.config.General.TLSVersionMax = tls.VersionTLS12
+		case "--tlsv1.3":
+			a.config.General.TLSVersionMin = tls.VersionTLS13
+			a.config.General.TLSVersionMax = tls.VersionTLS13
 		case "-1", "--tlsv1":
 			a.config.General.TLSVersionMin = tls.VersionTLS10
-			a.config.General.TLSVersionMax = tls.VersionTLS12
+			a.config.General.TLSVersionMax = tls.VersionTLS13
+		case "--ciphers":
+			if arg_index == args_len-1 {
+				return errors.New("No cipher suites specified")
+			}
+			arg_index += 1
+			a.config.General.CipherSuites = splitTLSList(args[arg_index])
+		case "--curves":
+			if arg_index == args_len-1 {
+				return errors.New("No curves specified")
+			}
+			arg_index += 1
+			a.config.General.Curves = splitTLSList(args[arg_index])
+		case "--sni":
+			if arg_index == args_len-1 {
+				return errors.New("No server name specified")
+			}
+			arg_index += 1
+			a.config.General.ServerName = args[arg_index]
 		case "-T", "--tls":
 			if arg_index >= args_len-1 {
 				return errors.New("Missing TLS version range: MIN,MAX")
@@ -1691,18 +2285,29 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			form_str := args[arg_index]
 			content_type = "multipart"
 			set_data = true
-			vdata, _ := g.View(REQUEST_DATA_VIEW)
-			setViewTextAndCursor(vdata, form_str)
+			r.Data = form_str
 		case "-f", "--file":
 			if arg_index == args_len-1 {
 				return errors.New("-f or --file requires a file path be provided as an argument")
 			}
 			arg_index += 1
-			loadLocation := args[arg_index]
-			a.LoadRequest(g, loadLocation)
+			stored, err := loadStoredRequest(args[arg_index])
+			if err != nil {
+				return err
+			}
+			a.applyStoredRequest(r, stored)
 		default:
 			u := args[arg_index]
-			if strings.Index(u, "http://") != 0 && strings.Index(u, "https://") != 0 {
+			if arg == "--url" {
+				if arg_index == args_len-1 {
+					return errors.New("Missing URL")
+				}
+				arg_index += 1
+				u = args[arg_index]
+			} else if strings.HasPrefix(u, "-") {
+				return fmt.Errorf("Unknown option: %v", u)
+			}
+			if strings.Index(u, "http://") != 0 && strings.Index(u, "https://") != 0 && !isWebSocketURL(u) && !isGRPCURL(u) {
 				u = fmt.Sprintf("%v://%v", a.config.General.DefaultURLScheme, u)
 			}
 			parsed_url, err := url.Parse(u)
@@ -1712,45 +2317,54 @@ func (a *App) ParseArgs(g *gocui.Gui, args []string) error {
 			if parsed_url.Path == "" {
 				parsed_url.Path = "/"
 			}
-			vurl, _ := g.View(URL_VIEW)
-			vurl.Clear()
 			for k, v := range parsed_url.Query() {
 				for _, vv := range v {
-					fmt.Fprintf(vget, "%v=%v\n", k, vv)
+					r.GetParams += fmt.Sprintf("%v=%v\n", k, vv)
 				}
 			}
 			parsed_url.RawQuery = ""
-			setViewTextAndCursor(vurl, parsed_url.String())
+			r.Url = parsed_url.String()
 		}
 		arg_index += 1
 	}
 
-	if set_data && !set_method {
-		vmethod, _ := g.View(REQUEST_METHOD_VIEW)
-		setViewTextAndCursor(vmethod, http.MethodPost)
+	if set_graphql {
+		r.GraphQL = true
 	}
 
-	if !set_binary_data && content_type != "" && !a.hasHeader(g, "Content-Type") {
-		fmt.Fprintf(vheader, "Content-Type: %v\n", config.ContentTypes[content_type])
+	if set_data && !set_method {
+		r.Method = http.MethodPost
 	}
 
-	if len(accept_types) > 0 && !a.hasHeader(g, "Accept") {
-		fmt.Fprintf(vheader, "Accept: %v\n", strings.Join(accept_types, ","))
+	if !set_binary_data && content_type != "" && !hasHeader(r.Headers, "Content-Type") {
+		r.Headers += fmt.Sprintf("Content-Type: %v\n", config.ContentTypes[content_type])
 	}
 
-	var merged_body_data string
-	if set_data && !set_binary_data {
-		merged_body_data = strings.Join(body_data, "&")
+	if len(accept_types) > 0 && !hasHeader(r.Headers, "Accept") {
+		r.Headers += fmt.Sprintf("Accept: %v\n", strings.Join(accept_types, ","))
 	}
 
-	vdata, _ := g.View(REQUEST_DATA_VIEW)
-	setViewTextAndCursor(vdata, merged_body_data)
+	if len(body_data) > 0 {
+		r.Data = strings.Join(body_data, "&")
+	}
 
 	return nil
 }
 
-func (a *App) hasHeader(g *gocui.Gui, h string) bool {
-	for _, header := range strings.Split(getViewValue(g, REQUEST_HEADERS_VIEW), "\n") {
+// viewHeaders returns the valid headers of the headers view
+func (a *App) viewHeaders(g *gocui.Gui) http.Header {
+	headers := http.Header{}
+	for _, header := range strings.Split(a.substituteVariables(getViewValue(g, REQUEST_HEADERS_VIEW)), "\n") {
+		if header_parts := strings.SplitN(header, ": ", 2); len(header_parts) == 2 {
+			headers.Set(header_parts[0], header_parts[1])
+		}
+	}
+	return headers
+}
+
+// hasHeader reports whether the "Name: value" lines contain header h
+func hasHeader(headers, h string) bool {
+	for _, header := range strings.Split(headers, "\n") {
 		if header == "" {
 			continue
 		}
@@ -1758,7 +2372,7 @@ func (a *App) hasHeader(g *gocui.Gui, h string) bool {
 		if len(header_parts) != 2 {
 			continue
 		}
-		if header_parts[0] == h {
+		if strings.EqualFold(header_parts[0], h) {
 			return true
 		}
 	}
@@ -1766,20 +2380,51 @@ func (a *App) hasHeader(g *gocui.Gui, h string) bool {
 }
 
 // Apply startup config values. This is run after a.ParseArgs, so that
-// args can override the provided config values
-func (a *App) InitConfig() {
-	CLIENT.Timeout = a.config.General.Timeout.Duration
-	TRANSPORT.TLSClientConfig = &tls.Config{
+// args can override the provided config values. A passphraseRequiredError
+// is returned after the rest of the config is applied.
+func (a *App) InitConfig() error {
+	tlsConfig := &tls.Config{
 		InsecureSkipVerify: a.config.General.Insecure,
 		MinVersion:         a.config.General.TLSVersionMin,
 		MaxVersion:         a.config.General.TLSVersionMax,
+		ServerName:         a.config.General.ServerName,
 	}
-	CLIENT.CheckRedirect = func(_ *http.Request, _ []*http.Request) error {
-		if a.config.General.FollowRedirects {
-			return nil
-		}
-		return http.ErrUseLastResponse
+	var err error
+	// cipher suites are not configurable for TLS 1.3
+	tlsConfig.CipherSuites, err = cipherSuiteIDs(a.config.General.CipherSuites)
+	if err != nil {
+		return err
 	}
+	tlsConfig.CurvePreferences, err = curveIDs(a.config.General.Curves)
+	if err != nil {
+		return err
+	}
+	CLIENT.CheckRedirect = a.checkRedirect
+	protoFiles, err := loadProtoFiles(a.config.General.ProtoFiles, a.config.General.ProtoImportPaths)
+	if err != nil {
+		return err
+	}
+	a.grpc.setConfig(protoFiles)
+	// the transport is set up without client certificates until the
+	// passphrase of an encrypted key is entered
+	certErr := a.initClientCertificates(tlsConfig)
+	if _, ok := certErr.(*passphraseRequiredError); certErr != nil && !ok {
+		return certErr
+	}
+	if TRANSPORT.Protocols, err = httpProtocols(a.config.General.HTTPVersion); err != nil {
+		return err
+	}
+	tlsConfig.NextProtos = alpnProtocols(TRANSPORT.Protocols)
+	TRANSPORT.TLSClientConfig = tlsConfig
+	if err := a.initDialOverrides(); err != nil {
+		return err
+	}
+	initGRPCTransport()
+	initWebSocketTransport()
+	if err := a.initCookieJar(); err != nil {
+		return err
+	}
+	return certErr
 }
 
 func refreshStatusLine(a *App, g *gocui.Gui) {
@@ -1820,27 +2465,67 @@ func help() {
 	fmt.Println(`wuzz - Interactive cli tool for HTTP inspection
 
 Usage: wuzz [-H|--header HEADER]... [-d|--data|--data-binary DATA] [-X|--request METHOD] [-t|--timeout MSECS] [URL]
+       wuzz test [--env NAME] [-k|--insecure] COLLECTION...
 
 Other command line options:
-  -c, --config PATH        Specify custom configuration file
+  -A, --user-agent NAME    Set the User-Agent header
+  -b, --cookie DATA|FILE   Send cookies in the Cookie header, or load a
+                           cookie file into the cookie jar
+  -c, --cookie-jar FILE    Enable the cookie jar and keep it in FILE
+  --batch, --print         Send the request without the TUI and print the
+                           formatted response body. The exit code is 0 for
+                           2xx responses, 3, 4 or 5 for 3xx, 4xx or 5xx
+                           responses, 6 for 1xx responses, 7 if an assertion
+                           fails, 1 if the request fails and 2 for invalid
+                           arguments
+  --cacert FILE            Verify servers with the PEM CA certificates of FILE
+  --ciphers LIST           Restrict allowed TLS1.0-1.2 cipher suites (comma separated)
+                           Example: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+  --config PATH            Specify custom configuration file
+  --connect-to H1:P1:H2:P2 Connect to H2:P2 instead of H1:P1, empty values match any host or port
+  --curves LIST            Set the preferred TLS key exchange curves (values: P-256,P-384,P-521,X25519,X25519MLKEM768)
+  -E, --cert FILE[:PASS]   Use a PEM client certificate for TLS authentication
   -e, --editor EDITOR      Specify external editor command
+  --env NAME               Activate a named environment from the config file
+  --auth NAME              Authenticate with a named auth from the config file
+  --sign NAME              Sign requests with a named signing from the config file
   -f, --file REQUEST       Load a previous request
   -F, --form DATA          Add multipart form request data and set related request headers
                            If the value starts with @ it will be handled as a file path for upload
+  --graphql                Edit the request data as a GraphQL query
   -h, --help               Show this
+  --http1.1                Use HTTP/1.1 only
+  --http2                  Negotiate HTTP/2 with TLS servers (default)
+  --http2-prior-knowledge  Use HTTP/2 only, without TLS via h2c
+  --import-path DIR        Resolve imports of .proto files in DIR
   -j, --json JSON          Add JSON request data and set related request headers
   -k, --insecure           Allow insecure SSL certs
+  --key FILE               Private key of the client certificate
+  -L, --location           Follow HTTP redirects
+  --max-redirs NUM         Follow at most NUM redirects (-1: unlimited)
+  --pass PHRASE            Passphrase of the encrypted client key
+  --proto FILE             Load gRPC services from a .proto file
+  --raw                    Print the response body unformatted in batch mode
   -R, --disable-redirects  Do not follow HTTP redirects
-  -T, --tls MIN,MAX        Restrict allowed TLS versions (values: SSL3.0,TLS1.0,TLS1.1,TLS1.2)
+  --resolve HOST:PORT:ADDR Connect to ADDR for HOST:PORT
+  --sni NAME               Send NAME as TLS server name and verify the certificate against it
+  -T, --tls MIN,MAX        Restrict allowed TLS versions (values: TLS1.0,TLS1.1,TLS1.2,TLS1.3)
                            Examples: wuzz -T TLS1.1        (TLS1.1 only)
                                      wuzz -T TLS1.0,TLS1.1 (from TLS1.0 up to TLS1.1)
   --tlsv1.0                Forces TLS1.0 only
   --tlsv1.1                Forces TLS1.1 only
   --tlsv1.2                Forces TLS1.2 only
-  -1, --tlsv1              Forces TLS version 1.x (1.0, 1.1 or 1.2)
+  --tlsv1.3                Forces TLS1.3 only
+  -1, --tlsv1              Forces TLS version 1.x (1.0, 1.1, 1.2 or 1.3)
+  -u, --user USER:PASS     Add basic authentication header
+  --unix-socket PATH       Connect through the unix socket PATH
   -v, --version            Display version number
   -x, --proxy URL          Set HTTP(S) or SOCKS5 proxy
 
+URLs with ws:// or wss:// scheme open a websocket session.
+URLs with grpc:// (cleartext HTTP/2) or grpcs:// scheme and a
+/package.Service/Method path call gRPC methods.
+
 Key bindings:
   ctrl+r              Send request
   ctrl+s              Save response
@@ -1849,6 +2534,26 @@ Key bindings:
   tab, ctrl+j         Next window
   shift+tab, ctrl+k   Previous window
   alt+h               Show history
+  alt+e               Switch environment
+  alt+c               Show collections
+  alt+i               Import curl command
+  alt+a               Load HAR file into history
+  alt+t               Show request timing
+  alt+s               Show TLS connection details
+  alt+g               Show gRPC methods
+  alt+q               Toggle GraphQL mode
+  alt+u               Select the auth of the requests
+  alt+n               Select the signing of the requests
+  alt+r               Show the headers of the sent request
+  alt+k               Show the cookies of the cookie jar
+  alt+j               Toggle the cookie jar
+  alt+l               Show the redirect chain
+  alt+d               Compare two responses from history
+  alt+x               Edit the assertions of the request
+  alt+p               Show the assertion results
+  alt+y               Edit the captures of the request
+  alt+v               Show the captured variables
+  ctrl+g              Cancel the request in flight
   pageUp              Scroll up the current window
   pageDown            Scroll down the current window`,
 	)
@@ -1856,6 +2561,7 @@ Key bindings:
 
 func main() {
 	configPath := ""
+	batch := false
 	args := os.Args
 	for i, arg := range os.Args {
 		switch arg {
@@ -1865,7 +2571,8 @@ func main() {
 		case "-v", "--version":
 			fmt.Printf("wuzz %v\n", VERSION)
 			return
-		case "-c", "--config":
+		// -c is the cookie jar like in curl
+		case "--config":
 			if i == len(os.Args)-1 {
 				log.Fatal("No config file specified")
 			}
@@ -1874,8 +2581,16 @@ func main() {
 			if _, err := os.Stat(configPath); os.IsNotExist(err) {
 				log.Fatal("Config file specified but does not exist: \"" + configPath + "\"")
 			}
+		case "--batch", "--print":
+			batch = true
 		}
 	}
+	if len(args) > 1 && args[1] == "test" {
+		os.Exit(runTests(configPath, args[2:]))
+	}
+	if batch {
+		os.Exit(runBatch(configPath, args))
+	}
 	var g *gocui.Gui
 	var err error
 	for _, outputMode := range []gocui.OutputMode{gocui.Output256, gocui.OutputNormal} {
@@ -1910,12 +2625,15 @@ func main() {
 		log.Fatalf("Error loading config file: %v", err)
 	}
 
+	// a missing or unreadable history store starts an empty history
+	app.LoadHistory()
+
 	err = app.ParseArgs(g, args)
 
 	// Some of the values in the config need to have some startup
 	// behavior associated with them. This is run after ParseArgs so
 	// that command-line arguments can override configuration values.
-	app.InitConfig()
+	configErr := app.InitConfig()
 
 	if err != nil {
 		g.Close()
@@ -1931,14 +2649,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	// encrypted client keys without a configured passphrase are
+	// unlocked from the TUI
+	if passphraseErr, ok := configErr.(*passphraseRequiredError); ok {
+		app.OpenPassphraseDialog(g, passphraseErr)
+	} else if configErr != nil {
+		g.Close()
+		fmt.Println("Error!", configErr)
+		os.Exit(1)
+	}
+
 	defer g.Close()
 
 	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
 		log.Panicln(err)
 	}
+	app.WaitHistory()
 }
 
-func exportJSON(r Request) []byte {
+func exportJSON(_ *App, r Request) []byte {
 	requestMap := map[string]string{
 		URL_VIEW:             r.Url,
 		REQUEST_METHOD_VIEW:  r.Method,
@@ -1946,6 +2675,21 @@ func exportJSON(r Request) []byte {
 		REQUEST_DATA_VIEW:    r.Data,
 		REQUEST_HEADERS_VIEW: r.Headers,
 	}
+	if r.GraphQL {
+		requestMap[GRAPHQL_VARIABLES_VIEW] = r.Variables
+	}
+	if r.Auth != "" {
+		requestMap[AUTH_VIEW] = r.Auth
+	}
+	if r.Signing != "" {
+		requestMap[SIGNING_VIEW] = r.Signing
+	}
+	if r.Assertions != "" {
+		requestMap[ASSERTIONS_VIEW] = r.Assertions
+	}
+	if r.Captures != "" {
+		requestMap[CAPTURES_VIEW] = r.Captures
+	}
 
 	request, err := json.Marshal(requestMap)
 	if err != nil {
@@ -1954,7 +2698,7 @@ func exportJSON(r Request) []byte {
 	return request
 }
 
-func exportCurl(r Request) []byte {
+func exportCurl(_ *App, r Request) []byte {
 	var headers, params string
 	for _, header := range strings.Split(r.Headers, "\n") {
 		if header == "" {
@@ -1963,7 +2707,7 @@ func exportCurl(r Request) []byte {
 		headers = fmt.Sprintf("%s -H %s", headers, shellescape.Quote(header))
 	}
 	if r.GetParams != "" {
-		params = fmt.Sprintf("?%s", r.GetParams)
+		params = fmt.Sprintf("?%s", strings.Replace(r.GetParams, "\n", "&", -1))
 	}
-	return []byte(fmt.Sprintf("curl %s -X %s -d %s %s\n", headers, r.Method, shellescape.Quote(r.Data), shellescape.Quote(r.Url+params)))
+	return []byte(fmt.Sprintf("curl %s -X %s -d %s %s\n", headers, r.Method, shellescape.Quote(r.body()), shellescape.Quote(r.Url+params)))
 }
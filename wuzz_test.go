@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	saved := Request{
+		Url:        "https://example.com/",
+		Method:     "POST",
+		GetParams:  "a=1",
+		Data:       "data",
+		Headers:    "Accept: text/plain",
+		GraphQL:    true,
+		Variables:  `{"id": 1}`,
+		Auth:       "api",
+		Signing:    "aws",
+		Assertions: "status == 200",
+		Captures:   "token = json data.token",
+	}
+	location := filepath.Join(t.TempDir(), "request.json")
+	if err := os.WriteFile(location, exportJSON(nil, saved), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadStoredRequest(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Url != saved.Url || loaded.Method != saved.Method || loaded.GetParams != saved.GetParams || loaded.Data != saved.Data || loaded.Headers != saved.Headers {
+		t.Errorf("Unexpected request: %+v", loaded)
+	}
+	if !loaded.GraphQL || loaded.Variables != saved.Variables {
+		t.Errorf("Expected GraphQL variables %v, got %v %v", saved.Variables, loaded.GraphQL, loaded.Variables)
+	}
+	if loaded.Auth != saved.Auth {
+		t.Errorf("Expected auth %v, got %v", saved.Auth, loaded.Auth)
+	}
+	if loaded.Signing != saved.Signing {
+		t.Errorf("Expected signing %v, got %v", saved.Signing, loaded.Signing)
+	}
+	if loaded.Assertions != saved.Assertions {
+		t.Errorf("Expected assertions %v, got %v", saved.Assertions, loaded.Assertions)
+	}
+	if loaded.Captures != saved.Captures {
+		t.Errorf("Expected captures %v, got %v", saved.Captures, loaded.Captures)
+	}
+}